@@ -2,15 +2,35 @@ package app
 
 import (
 	"github.com/urfave/cli/v2"
+	"tasky/config"
+	"tasky/utils"
 )
 
 // NewApp creates and configures a new urfave/cli application.
 func NewApp() *cli.App {
 	app := &cli.App{
-		Name:    "tasky",
-		Usage:   "A command-line task manager",
-		Version: "1.1.0", // You can manage your version here
+		Name:     "tasky",
+		Usage:    "A command-line task manager",
+		Version:  "1.1.0", // You can manage your version here
 		Commands: GetCommands(),
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "non-interactive",
+				Usage:   "Never prompt: questions get their default answer and yes/no questions are answered no",
+				EnvVars: []string{"TASKY_NON_INTERACTIVE"},
+			},
+			&cli.StringFlag{
+				Name:  "vault",
+				Usage: "Use the vault with this name in the [vaults] config table, or at this path",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			utils.SetInteractive(!c.Bool("non-interactive"))
+			if c.IsSet("vault") {
+				config.SetOverride("general.vault", c.String("vault"), "--vault flag")
+			}
+			return nil
+		},
 	}
 	return app
 }
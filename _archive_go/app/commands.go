@@ -8,12 +8,18 @@ import (
 // GetCommands returns a slice of all top-level CLI commands.
 func GetCommands() []*cli.Command {
 	return []*cli.Command{
+		cmd.InitCommand(),
 		cmd.NewCommand(),
 		cmd.ListCommand(),
 		cmd.DoneCommand(),
+		cmd.SetCommand(),
+		cmd.EditCommand(),
 		cmd.StartCommand(),
 		cmd.FinishCommand(),
 		cmd.PomodoroCommand(),
 		cmd.LinkCommand(),
+		cmd.MigrateLayoutCommand(),
+		cmd.UICommand(),
+		cmd.ConfigCommand(),
 	}
 }
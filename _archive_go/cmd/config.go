@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"tasky/config"
+)
+
+// ConfigCommand returns a *cli.Command for the "config" command.
+func ConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Inspect the configuration",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "show",
+				Usage: "Print the effective value of every setting",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "origin",
+						Aliases: []string{"o"},
+						Usage:   "Also print where each value comes from: default, a config file, an environment variable or a flag",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					cfg, origins, err := loadConfigWithOrigins()
+					if err != nil {
+						return err
+					}
+					keys, values := config.Settings(cfg)
+					width := 0
+					for _, key := range keys {
+						width = max(width, len(key)+len(values[key]))
+					}
+					for _, key := range keys {
+						line := key + " = " + values[key]
+						if c.Bool("origin") {
+							line += strings.Repeat(" ", width-len(key)-len(values[key])) + "  # " + origins[key]
+						}
+						fmt.Println(line)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 	taskyconfig "tasky/config"
+	"tasky/query"
 	tasky "tasky/task"
 	"tasky/utils"
 )
@@ -14,21 +15,74 @@ func DoneCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "done",
 		Usage:     "Mark a task as done",
-		UsageText: "tasky done <task_title>",
+		UsageText: "tasky done <task_id|issue_number|title>\n   tasky done --query \"<query>\"",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "query",
+				Aliases: []string{"q"},
+				Usage:   "Mark every task of the current project matching this query as done (see tasky list)",
+			},
+		},
 		Action: func(c *cli.Context) error {
+			if c.IsSet("query") {
+				return markMatchingTasksDone(c.String("query"))
+			}
 			if c.NArg() < 1 {
-				return cli.Exit("Usage: tasky done <task_title>", 1)
+				return cli.Exit("Usage: tasky done <task_id|issue_number|title>", 1)
+			}
+			taskRef := c.Args().Get(0)
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
 			}
-			taskTitle := c.Args().Get(0)
-			cfg := taskyconfig.LoadConfig()
-			if err := tasky.MarkTaskDone(cfg, taskTitle); err != nil {
+			svc := tasky.NewService(cfg)
+			if _, err := svc.MarkDone(svc.Project(), taskRef); err != nil {
 				return cli.Exit(err.Error(), 1)
 			}
 			if err := utils.PlaySound(cfg.Sounds.Done); err != nil {
 				fmt.Printf("Warning: %v\n", err)
 			}
-			fmt.Printf("Task '%s' marked as done.\n", taskTitle)
+			fmt.Printf("Task '%s' marked as done.\n", taskRef)
 			return nil
 		},
 	}
 }
+
+// markMatchingTasksDone marks every not yet done task of the current project matching expr as done.
+func markMatchingTasksDone(expr string) error {
+	q, err := query.Parse(expr)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Invalid query: %v", err), 1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	svc := tasky.NewService(cfg)
+	projectName := svc.Project()
+	entries, err := svc.List(projectName, q.NeedsDescription())
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Error listing tasks: %v", err), 1)
+	}
+
+	marked := 0
+	for _, e := range q.Filter(entries) {
+		if e.Status == taskyconfig.StatusDone {
+			continue
+		}
+		if _, err := svc.MarkDone(projectName, e.ID); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		fmt.Printf("Task '%s' marked as done.\n", e.Title)
+		marked++
+	}
+	if marked == 0 {
+		fmt.Println("No matching task to mark as done.")
+		return nil
+	}
+	if err := utils.PlaySound(cfg.Sounds.Done); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return nil
+}
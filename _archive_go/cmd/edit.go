@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"tasky/task"
+	"tasky/utils"
+)
+
+// errorLinePrefix starts the lines telling in an edited note why it was rejected.
+const errorLinePrefix = "# tasky error: "
+
+// EditCommand returns a *cli.Command for the "edit" command.
+func EditCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "edit",
+		Usage:     "Edit the note of a task in $EDITOR, checking its frontmatter on save",
+		UsageText: "tasky edit <task_id|issue_number|title>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return cli.Exit("Usage: tasky edit <task_id|issue_number|title>", 1)
+			}
+			if !utils.Interactive() {
+				return cli.Exit("tasky edit needs an editor and cannot run in non-interactive mode; use tasky set.", 1)
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			svc := task.NewService(cfg)
+			e, original, err := svc.ReadNote(svc.Project(), c.Args().Get(0))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+
+			// The note is edited as a copy, written back only once valid.
+			tmp, err := os.CreateTemp("", "tasky-*-"+filepath.Base(e.Path))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+			defer os.Remove(tmp.Name())
+			_, err = tmp.Write(original)
+			if closeErr := tmp.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+
+			for {
+				if err := utils.RunEditor(tmp.Name()); err != nil {
+					return cli.Exit(fmt.Sprintf("Editor failed: %v", err), 1)
+				}
+				edited, err := os.ReadFile(tmp.Name())
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+				}
+				edited = stripErrorLines(edited)
+				if len(bytes.TrimSpace(edited)) == 0 {
+					fmt.Println("The note is empty: edit aborted.")
+					return nil
+				}
+				if bytes.Equal(edited, original) {
+					fmt.Println("No changes.")
+					return nil
+				}
+				saved, err := svc.SaveNote(e, original, edited)
+				if errors.Is(err, task.ErrInvalid) {
+					// Reopen the note with the error, until it is fixed or emptied.
+					if err := os.WriteFile(tmp.Name(), inlineError(edited, err), 0600); err != nil {
+						return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+					}
+					continue
+				}
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+				}
+				fmt.Printf("Task '%s' updated.\n", saved.Title)
+				return nil
+			}
+		},
+	}
+}
+
+// inlineError adds err to content as YAML comments after the opening line of the
+// frontmatter, or at the top of a note without frontmatter, with a hint to abort.
+func inlineError(content []byte, err error) []byte {
+	var lines []string
+	for _, line := range strings.Split(err.Error(), "\n") {
+		lines = append(lines, errorLinePrefix+line+"\n")
+	}
+	lines = append(lines, errorLinePrefix+"fix the note and save it, or empty it to abort\n")
+	comment := []byte(strings.Join(lines, ""))
+	if bytes.HasPrefix(content, []byte("---\n")) {
+		return append(append([]byte("---\n"), comment...), content[len("---\n"):]...)
+	}
+	return append(comment, content...)
+}
+
+// stripErrorLines removes the lines added by inlineError.
+func stripErrorLines(content []byte) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	kept := lines[:0]
+	for _, line := range lines {
+		if !bytes.HasPrefix(line, []byte(errorLinePrefix)) {
+			kept = append(kept, line)
+		}
+	}
+	return bytes.Join(kept, nil)
+}
@@ -1,26 +1,91 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/urfave/cli/v2"
-	"tasky/config"
+	"tasky/forge"
 	"tasky/task"
+	"tasky/utils"
 )
 
 // FinishCommand returns a *cli.Command for the "finish" command.
 func FinishCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "finish",
-		Usage: "Close a GitHub issue, merge PR, and mark task done",
+		Name:      "finish",
+		Usage:     "Merge a pull request closing the issue of the current branch, and mark its task done",
+		UsageText: "tasky finish [--dry-run] [--interactive] [--merge <strategy>] [--no-merge] [--draft] [--keep-branch] [--resume]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"n"},
+				Usage:   "Print the git and forge commands and the note changes without running them",
+			},
+			&cli.BoolFlag{
+				Name:    "interactive",
+				Aliases: []string{"i"},
+				Usage:   "Confirm each step",
+			},
+			&cli.StringFlag{
+				Name:  "merge",
+				Value: forge.MergeSquash,
+				Usage: fmt.Sprintf("Merge strategy: %s, %s or %s", forge.MergeSquash, forge.MergeCommit, forge.MergeRebase),
+			},
+			&cli.BoolFlag{
+				Name:  "no-merge",
+				Usage: "Only open the pull request",
+			},
+			&cli.BoolFlag{
+				Name:  "draft",
+				Usage: "Open a draft pull request, which is not merged",
+			},
+			&cli.BoolFlag{
+				Name:  "keep-branch",
+				Usage: "Do not delete the branch after merging",
+			},
+			&cli.BoolFlag{
+				Name:  "resume",
+				Usage: "Resume the branch an earlier run left unfinished when the current branch has no task, without asking",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			cfg := config.LoadConfig()
-			if err := task.FinishTask(cfg); err != nil {
-				return cli.Exit(fmt.Sprintf("Error finishing task: %v\n", err), 1)
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			opts := task.FinishOptions{
+				Out:        os.Stdout,
+				DryRun:     c.Bool("dry-run"),
+				Strategy:   c.String("merge"),
+				NoMerge:    c.Bool("no-merge"),
+				Draft:      c.Bool("draft"),
+				KeepBranch: c.Bool("keep-branch"),
+				Resume: func(branch string) bool {
+					return confirmFlag(c, "resume", fmt.Sprintf("The current branch has no task. Resume finishing branch %s? (Y/n): ", branch))
+				},
+			}
+			if c.Bool("interactive") {
+				if !utils.Interactive() {
+					return cli.Exit("--interactive confirms each step and cannot be used in non-interactive mode.", 1)
+				}
+				opts.Confirm = func(step string) bool {
+					return utils.Confirm(step + "? (Y/n): ")
+				}
+			}
+			err = task.NewService(cfg).Finish(opts)
+			if errors.Is(err, task.ErrStopped) {
+				fmt.Printf("Finishing %v\n", err)
+				return nil
+			}
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error finishing task: %v", err), 1)
+			}
+			if !opts.DryRun {
+				fmt.Println("Task finished successfully.")
 			}
-			fmt.Println("Task finished successfully.")
 			return nil
 		},
 	}
 }
-
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"tasky/config"
+	"tasky/utils"
+)
+
+// defaultVaultPath is the vault proposed when setting tasky up.
+const defaultVaultPath = "~/Documents/Obsidian"
+
+// InitCommand returns a *cli.Command for the "init" command.
+func InitCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "init",
+		Usage:     "Set tasky up by creating its config file",
+		UsageText: "tasky init [--vault-path <path>] [--force]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "vault-path",
+				Usage: "Path of the Obsidian vault, asked for when not given (default " + defaultVaultPath + ")",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Replace an existing config file",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			path, err := setUp(c.String("vault-path"), c.Bool("force"))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+			fmt.Printf("Configuration saved to %s.\n", path)
+			return nil
+		},
+	}
+}
+
+// setUp creates the global config file for the vault at vaultPath, asking for it when
+// it is empty.
+func setUp(vaultPath string, overwrite bool) (string, error) {
+	if configPath, err := config.ConfigPath(); err == nil && !overwrite {
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath, fmt.Errorf("%s already exists, use --force to replace it", configPath)
+		}
+	}
+	if vaultPath == "" {
+		vaultPath = utils.Ask("Press Enter to use the default path (" + defaultVaultPath + ") or enter a custom path: ")
+	}
+	if vaultPath == "" {
+		vaultPath = defaultVaultPath
+	}
+	return config.Init(vaultPath, overwrite)
+}
+
+// confirmFlag answers question with the boolean flag when it is given, and otherwise
+// asks, see utils.Confirm. Flags let scripts opt in to what non-interactive mode skips.
+func confirmFlag(c *cli.Context, flag, question string) bool {
+	if c.IsSet(flag) {
+		return c.Bool(flag)
+	}
+	return utils.Confirm(question)
+}
+
+// loadConfig loads the configuration of a command, see loadConfigWithOrigins.
+func loadConfig() (config.Config, error) {
+	cfg, _, err := loadConfigWithOrigins()
+	return cfg, err
+}
+
+// loadConfigWithOrigins loads the configuration of a command and where each setting comes
+// from. On the first run, it sets tasky up unless prompts are disabled.
+func loadConfigWithOrigins() (config.Config, config.Origins, error) {
+	cfg, origins, err := config.LoadConfigWithOrigins()
+	if errors.Is(err, config.ErrNotConfigured) && utils.Interactive() {
+		fmt.Print("Welcome to Tasky! ")
+		if _, err = setUp("", false); err == nil {
+			fmt.Println("Configuration saved.")
+			cfg, origins, err = config.LoadConfigWithOrigins()
+		}
+	}
+	if err != nil {
+		return cfg, origins, cli.Exit(err.Error(), 1)
+	}
+	return cfg, origins, nil
+}
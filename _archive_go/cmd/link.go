@@ -1,12 +1,8 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
-	"tasky/config"
 	"tasky/utils"
 
 	"github.com/urfave/cli/v2"
@@ -17,14 +13,26 @@ func LinkCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "link",
 		Usage: "Create a symbolic link to the project's task directory in the current directory",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "gitignore",
+				Usage: "Add the link to the project's .gitignore, without asking",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			cfg := config.LoadConfig()
-			projectName := utils.GetProjectName()
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			projectName := utils.GetProjectName(cfg)
 			if projectName == "unknown_project" {
 				return cli.Exit("Could not determine project name. Please run this command in a Git repository.", 1)
 			}
 
-			targetPath := filepath.Join(cfg.General.VaultPath, "Tasky", projectName)
+			if cfg.Storage.Backend == utils.BackendBolt {
+				return cli.Exit("The bolt storage backend keeps no task directory to link to.", 1)
+			}
+			targetPath := utils.TaskyDir(cfg, projectName)
 			linkPath := "_tasky"
 
 			// Check if the target directory exists
@@ -48,12 +56,7 @@ func LinkCommand() *cli.Command {
 			fmt.Printf("Symbolic link '%s' created successfully.\n", linkPath)
 
 			// Ask to add to .gitignore
-			fmt.Print("Add '_tasky/' to your project's .gitignore? (Y/n): ")
-			reader := bufio.NewReader(os.Stdin)
-			input, _ := reader.ReadString('\n')
-			trimmedInput := strings.ToLower(strings.TrimSpace(input))
-
-			if trimmedInput == "y" || trimmedInput == "" {
+			if confirmFlag(c, "gitignore", "Add '_tasky/' to your project's .gitignore? (Y/n): ") {
 				gitignorePath := ".gitignore"
 				file, err := os.OpenFile(gitignorePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 				if err != nil {
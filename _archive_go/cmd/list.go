@@ -2,63 +2,103 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/urfave/cli/v2"
-	"tasky/config"
+	"tasky/output"
+	"tasky/query"
 	"tasky/task"
 	"tasky/utils"
 )
 
-func getStatusSymbol(status string) string {
-	switch status {
-	case config.StatusTodo:
-		return config.SymbolTodo
-	case config.StatusInProgress:
-		return config.SymbolDoing
-	case config.StatusDone:
-		return config.SymbolDone
-	default:
-		return "?"
-	}
-}
-
 // ListCommand returns a *cli.Command for the "list" command.
 func ListCommand() *cli.Command {
 	return &cli.Command{
-		Name:    "list",
-		Aliases: []string{"view"},
-		Usage:   "List tasks",
+		Name:      "list",
+		Aliases:   []string{"view"},
+		Usage:     "List tasks, optionally filtered by a query",
+		UsageText: "tasky list [--all] [--project name] [--sort fields] [--limit n] [query...]\n\nQuery terms: status:todo issue:>100 created:>=2026-01-01 pomodoros:>3 tag:backend -status:done \"free text\"",
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:    "all",
 				Aliases: []string{"a"},
 				Usage:   "List all tasks, regardless of project",
 			},
+			&cli.StringFlag{
+				Name:    "project",
+				Aliases: []string{"p"},
+				Usage:   "List the tasks of this project instead of the current one",
+			},
+			&cli.StringFlag{
+				Name:    "sort",
+				Aliases: []string{"s"},
+				Usage:   "Comma separated sort fields, prefix with '-' for descending (e.g. status,-created)",
+			},
+			&cli.IntFlag{
+				Name:    "limit",
+				Aliases: []string{"n"},
+				Usage:   "Show at most this many tasks",
+			},
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   output.FormatUsage,
+				Value:   output.FormatText,
+			},
 		},
 		Action: func(c *cli.Context) error {
-			cfg := config.LoadConfig()
-			var tasks []config.Task
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
 
-			if c.Bool("all") {
-				tasks = task.GetTasks(cfg, "") // Get all tasks
-			} else {
-				projectName := c.Args().First()
-				if projectName == "" {
-					projectName = utils.GetProjectName()
-					if projectName == "unknown_project" {
-						return cli.Exit("Usage: tasky list [project_name] or tasky list --all. Run in a Git repository or provide a project name.", 1)
-					}
+			args := c.Args().Slice()
+			projectName := c.String("project")
+			// `tasky list <project>` predates queries and is still accepted.
+			if projectName == "" && len(args) == 1 && !strings.ContainsAny(args[0], ": ") {
+				if projects, err := task.ListProjects(cfg); err == nil && slices.Contains(projects, args[0]) {
+					projectName = args[0]
+					args = nil
 				}
-				tasks = task.GetTasks(cfg, projectName)
 			}
 
-			for _, t := range tasks {
-				if t.Issue != 0 {
-					fmt.Printf("%s #%d %s\n", getStatusSymbol(t.Status), t.Issue, t.Title)
-				} else {
-					fmt.Printf("%s %s\n", getStatusSymbol(t.Status), t.Title)
+			q, err := query.Parse(strings.Join(args, " "))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Invalid query: %v", err), 1)
+			}
+			sortKeys, err := query.ParseSort(c.String("sort"))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Invalid sort: %v", err), 1)
+			}
+			format := c.String("format")
+			if err := output.Validate(format); err != nil {
+				return cli.Exit(fmt.Sprintf("Invalid format: %v", err), 1)
+			}
+
+			if c.Bool("all") || q.HasField("project") {
+				projectName = ""
+			} else if projectName == "" {
+				projectName = utils.GetProjectName(cfg)
+				if projectName == "unknown_project" {
+					return cli.Exit("Usage: tasky list [--project name] [query] or tasky list --all. Run in a Git repository or provide a project name.", 1)
 				}
 			}
+
+			entries, err := task.ListEntries(cfg, projectName, q.NeedsDescription() || output.NeedsDescription(format))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error listing tasks: %v", err), 1)
+			}
+			entries = q.Filter(entries)
+			query.Sort(entries, sortKeys)
+			if limit := c.Int("limit"); limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+
+			if err := output.Write(os.Stdout, format, entries); err != nil {
+				return cli.Exit(fmt.Sprintf("Error printing tasks: %v", err), 1)
+			}
 			return nil
 		},
 	}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+	"tasky/config"
+	"tasky/utils"
+)
+
+// MigrateLayoutCommand returns a *cli.Command for the "migrate-layout" command.
+func MigrateLayoutCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "migrate-layout",
+		Usage:     "Move the notes of every project to another layout of the vault, such as Tasky/{project}",
+		UsageText: "tasky migrate-layout [--from <layout>] [--dry-run] [--yes] <layout>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "Layout the notes are in (default storage.layout)",
+			},
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"n"},
+				Usage:   "Print the moves without making them",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Make the moves without asking",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return cli.Exit("Usage: tasky migrate-layout [--from <layout>] [--dry-run] [--yes] <layout>", 1)
+			}
+			to := c.Args().Get(0)
+			cfg, origins, err := loadConfigWithOrigins()
+			if err != nil {
+				return err
+			}
+			if cfg.Storage.Backend == utils.BackendBolt {
+				return cli.Exit("The bolt storage backend keeps no notes in the vault.", 1)
+			}
+			from := c.String("from")
+			if from == "" {
+				from = cfg.Storage.LayoutTemplate()
+			}
+
+			vault := cfg.General.VaultPath
+			moves, err := utils.PlanLayoutMigration(vault, from, to, cfg.NoteFormat)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+			for _, move := range moves {
+				fmt.Printf("mv %s %s\n", relativePath(vault, move.From), relativePath(vault, move.To))
+			}
+			if len(moves) == 0 {
+				fmt.Printf("No notes to move from %s.\n", from)
+			}
+			if c.Bool("dry-run") {
+				return nil
+			}
+			if len(moves) > 0 {
+				if !confirmFlag(c, "yes", fmt.Sprintf("Move %d files and directories? (Y/n): ", len(moves))) {
+					fmt.Println("No notes moved.")
+					return nil
+				}
+				if err := utils.ApplyLayoutMigration(vault, from, to, cfg.NoteFormat, moves); err != nil {
+					return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+				}
+				fmt.Printf("Notes moved to %s.\n", to)
+			}
+			return saveLayout(to, origins["storage.layout"])
+		},
+	}
+}
+
+// saveLayout sets storage.layout in the global config file, unless a layer above it,
+// given by origin, would override it.
+func saveLayout(layout, origin string) error {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+	}
+	if origin != config.OriginDefault && origin != configPath {
+		fmt.Printf("storage.layout is set by %s: set it to \"%s\" there.\n", origin, layout)
+		return nil
+	}
+	cfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+	}
+	if cfg.Storage.LayoutTemplate() == layout {
+		return nil
+	}
+	cfg.Storage.Layout = layout
+	if err := config.SaveConfig(cfg); err != nil {
+		return cli.Exit(fmt.Sprintf("Error saving config: %v", err), 1)
+	}
+	fmt.Printf("storage.layout set to \"%s\" in %s.\n", layout, configPath)
+	return nil
+}
+
+// relativePath returns path relative to vault when it is inside it.
+func relativePath(vault, path string) string {
+	if rel, err := filepath.Rel(vault, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -1,13 +1,10 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
-	"strings"
 
-	"tasky/config"
+	"tasky/forge"
 	"tasky/pomodoro"
 	"tasky/task"
 	"tasky/utils"
@@ -18,19 +15,30 @@ import (
 // NewCommand returns a *cli.Command for the "new" command.
 func NewCommand() *cli.Command {
 	return &cli.Command{
-		Name:	  "new",
-		Usage:	  "Create a new task",
-		UsageText: "tasky new \"<title>\" [\"<description>\"]",
+		Name:      "new",
+		Usage:     "Create a new task",
+		UsageText: "tasky new [--issue] [--create-project] [--start] \"<title>\" [\"<description>\"]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "issue",
+				Usage: "Open an issue for the task on the forge, without asking",
+			},
+			&cli.BoolFlag{
+				Name:  "create-project",
+				Usage: "Create the project if it does not exist yet, without asking",
+			},
+			&cli.BoolFlag{
+				Name:  "start",
+				Usage: "Start the task, checking out its branch when it has an issue, without asking",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			var title string
-			reader := bufio.NewReader(os.Stdin)
-			var input string
-			var trimmedInput string
-
 			if c.NArg() < 1 {
-				fmt.Print("Enter task title: ")
-				inputTitle, _ := reader.ReadString('\n')
-				title = strings.TrimSpace(inputTitle)
+				if !utils.Interactive() {
+					return cli.Exit("Usage: tasky new \"<title>\" [\"<description>\"]", 1)
+				}
+				title = utils.Ask("Enter task title: ")
 				if title == "" {
 					return cli.Exit("Task title cannot be empty.", 1)
 				}
@@ -43,49 +51,49 @@ func NewCommand() *cli.Command {
 				description = c.Args().Get(1)
 			}
 
-			createGitHubIssue := false
-			if utils.IsGitRepository() && utils.HasGitHubRemote() {
-				fmt.Print("Create a GitHub issue? (Y/n): ")
-				input, _ = reader.ReadString('\n')
-				trimmedInput = strings.ToLower(strings.TrimSpace(input))
-				if trimmedInput == "y" || trimmedInput == "" {
-					createGitHubIssue = true
-				}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			createIssue, forgeName := false, ""
+			if f, err := forge.New(cfg); err == nil {
+				forgeName = f.Name()
+				createIssue = confirmFlag(c, "issue", fmt.Sprintf("Create a %s issue? (Y/n): ", forgeName))
 			}
 
-			cfg := config.LoadConfig()
-			issueNumberStr, filePath, err := task.CreateTask(cfg, title, description, createGitHubIssue)
+			svc := task.NewService(cfg)
+			opts := task.CreateOptions{Description: description, CreateIssue: createIssue}
+			created, err := svc.Create(title, opts)
+			if errors.Is(err, task.ErrNotFound) && confirmFlag(c, "create-project", fmt.Sprintf("Project '%s' does not exist. Create it? (Y/n): ", svc.Project())) {
+				opts.CreateProject = true
+				created, err = svc.Create(title, opts)
+			}
+			if errors.Is(err, task.ErrNotFound) && !utils.Interactive() {
+				return cli.Exit(fmt.Sprintf("Error creating task: %v (use --create-project to create it)", err), 1)
+			}
 			if err != nil {
 				return cli.Exit(fmt.Sprintf("Error creating task: %v", err), 1)
 			}
-			fmt.Printf("Task '%s' created successfully.\nFile path: %s\n", title, filePath)
+			if created.Issue != nil {
+				fmt.Printf("%s issue created successfully.\n%s\n", forgeName, created.Issue.URL)
+			}
+			fmt.Printf("Task '%s' created successfully (ID %s).\nFile path: %s\n", title, created.ID, created.Path)
 
 			// Ask to start the task
-			fmt.Print("Start this task? (Y/n): ")
-			input, _ = reader.ReadString('\n')
-			trimmedInput = strings.ToLower(strings.TrimSpace(input))
-
-			if trimmedInput == "y" || trimmedInput == "" {
-				if issueNumberStr != "" {
-					// If GitHub issue was created, start development on it
-					if err := task.StartTaskDevelopment(issueNumberStr); err != nil {
-						return cli.Exit(fmt.Sprintf("Error starting task development: %v", err), 1)
+			if confirmFlag(c, "start", "Start this task? (Y/n): ") {
+				if created.Issue != nil {
+					// If an issue was created, start development on it
+					if err := startDevelopment(svc, &created.Task, created.Task.Issue, ""); err != nil {
+						return err
 					}
-					issueNumber, _ := strconv.Atoi(issueNumberStr)
-					task.MarkTaskInProgress(cfg, issueNumber)
-				} else {
-					task.MarkTaskInProgressByTitle(cfg, title)
 				}
+				markInProgress(svc, created.Project, &created.Task)
 				if err := utils.PlaySound(cfg.Sounds.Start); err != nil {
 					fmt.Printf("Warning: %v\n", err)
 				}
 
-				// Ask to start a Pomodoro
-				fmt.Print("Start a Pomodoro? (Y/n): ")
-				input, _ = reader.ReadString('\n')
-				trimmedInput = strings.ToLower(strings.TrimSpace(input))
-
-				if trimmedInput == "y" || trimmedInput == "" {
+				// Ask to start a Pomodoro, which would block a script
+				if utils.Interactive() && utils.Confirm("Start a Pomodoro? (Y/n): ") {
 					pomodoro.StartPomodoroCycle(cfg)
 				}
 			}
@@ -93,4 +101,4 @@ func NewCommand() *cli.Command {
 			return nil
 		},
 	}
-}
\ No newline at end of file
+}
@@ -1,15 +1,17 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"tasky/config"
 	"tasky/pomodoro"
+	"tasky/session"
+	"tasky/task"
+	"tasky/utils"
 )
 
 // PomodoroCommand returns a *cli.Command for the "pomodoro" command.
@@ -22,12 +24,116 @@ func PomodoroCommand() *cli.Command {
 			{
 				Name:  "start",
 				Usage: "Start a Pomodoro timer",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "detach",
+						Aliases: []string{"d"},
+						Usage:   "Run the timer in the background, controlled with status, pause, resume and stop",
+					},
+				},
 				Action: func(c *cli.Context) error {
-					cfg := config.LoadConfig()
+					cfg, err := loadConfig()
+					if err != nil {
+						return err
+					}
+					if c.Bool("detach") {
+						status, err := pomodoro.Detach()
+						if err != nil {
+							return cli.Exit(err.Error(), 1)
+						}
+						fmt.Println("Pomodoro started in the background.")
+						printPomodoroStatus(status)
+						return nil
+					}
 					pomodoro.StartPomodoroCycle(cfg)
 					return nil
 				},
 			},
+			{
+				Name:  "status",
+				Usage: "Show the state of the background Pomodoro",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "short",
+						Aliases: []string{"s"},
+						Usage:   "Print a single line for shell prompts and status bars, or nothing when no Pomodoro runs",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					status, err := pomodoro.Send(pomodoro.CommandStatus)
+					if c.Bool("short") {
+						if err == nil {
+							fmt.Println(status.Short())
+						}
+						return nil
+					}
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					printPomodoroStatus(status)
+					return nil
+				},
+			},
+			pomodoroControlCommand(pomodoro.CommandPause, "Pause the background Pomodoro"),
+			pomodoroControlCommand(pomodoro.CommandResume, "Resume the background Pomodoro, or start its next phase"),
+			pomodoroControlCommand(pomodoro.CommandStop, "Stop the background Pomodoro"),
+			{
+				Name:   "daemon",
+				Usage:  "Run the background Pomodoro (started by start --detach)",
+				Hidden: true,
+				Action: func(c *cli.Context) error {
+					cfg, err := loadConfig()
+					if err != nil {
+						return err
+					}
+					if err := pomodoro.RunDaemon(cfg); err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "log",
+				Usage:     "Show the logged Pomodoro sessions",
+				UsageText: "tasky pomodoro log [--task <task_id|issue_number|title>]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "task",
+						Aliases: []string{"t"},
+						Usage:   "Only show the sessions of this task of the current project",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					cfg, err := loadConfig()
+					if err != nil {
+						return err
+					}
+					sessions, err := session.Read(cfg)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("Error reading session log: %v", err), 1)
+					}
+					if ref := c.String("task"); ref != "" {
+						projectName := utils.GetProjectName(cfg)
+						t, _, err := task.FindTask(cfg, projectName, ref)
+						if err != nil {
+							return cli.Exit(err.Error(), 1)
+						}
+						sessions = session.ForTask(sessions, projectName, t.ID)
+					}
+					for _, s := range sessions {
+						status := "completed"
+						if !s.Completed {
+							status = "aborted"
+						}
+						target := ""
+						if s.TaskID != "" {
+							target = s.Project + "/" + s.TaskID
+						}
+						fmt.Printf("%s  %-11s %-9s %8s  %s\n", s.Start.Format("2006-01-02 15:04"), s.Kind, status, s.Elapsed.Round(time.Second), target)
+					}
+					return nil
+				},
+			},
 		},
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
@@ -38,42 +144,44 @@ func PomodoroCommand() *cli.Command {
 		},
 		Action: func(c *cli.Context) error {
 			if c.Bool("configure") {
-				reader := bufio.NewReader(os.Stdin)
-				cfg := config.LoadConfig()
+				if !utils.Interactive() {
+					return cli.Exit("pomodoro --configure asks questions: edit the config file or set TASKY_POMODORO_* instead.", 1)
+				}
+				cfg, err := config.LoadGlobalConfig()
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
 
-				fmt.Print("What is the duration of a single Pomodoro (in minutes)? ")
-				pomodoroDurationStr, _ := reader.ReadString('\n')
-				pomodoroDuration, err := strconv.Atoi(strings.TrimSpace(pomodoroDurationStr))
+				pomodoroDuration, err := strconv.Atoi(utils.Ask("What is the duration of a single Pomodoro (in minutes)? "))
 				if err != nil {
 					return cli.Exit("Invalid input. Please enter a number.", 1)
 				}
 				cfg.Pomodoro.PomodoroDuration = pomodoroDuration
 
-				fmt.Print("What is the duration of a short break (in minutes)? ")
-				shortBreakDurationStr, _ := reader.ReadString('\n')
-				shortBreakDuration, err := strconv.Atoi(strings.TrimSpace(shortBreakDurationStr))
+				shortBreakDuration, err := strconv.Atoi(utils.Ask("What is the duration of a short break (in minutes)? "))
 				if err != nil {
 					return cli.Exit("Invalid input. Please enter a number.", 1)
 				}
 				cfg.Pomodoro.ShortBreakDuration = shortBreakDuration
 
-				fmt.Print("What is the duration of a long break (in minutes)? ")
-				longBreakDurationStr, _ := reader.ReadString('\n')
-				longBreakDuration, err := strconv.Atoi(strings.TrimSpace(longBreakDurationStr))
+				longBreakDuration, err := strconv.Atoi(utils.Ask("What is the duration of a long break (in minutes)? "))
 				if err != nil {
 					return cli.Exit("Invalid input. Please enter a number.", 1)
 				}
 				cfg.Pomodoro.LongBreakDuration = longBreakDuration
 
-				fmt.Print("After how many Pomodoros should a long break occur? ")
-				longBreakIntervalStr, _ := reader.ReadString('\n')
-				longBreakInterval, err := strconv.Atoi(strings.TrimSpace(longBreakIntervalStr))
+				longBreakInterval, err := strconv.Atoi(utils.Ask("After how many Pomodoros should a long break occur? "))
 				if err != nil {
 					return cli.Exit("Invalid input. Please enter a number.", 1)
 				}
 				cfg.Pomodoro.LongBreakInterval = longBreakInterval
 
-				config.SaveConfig(cfg)
+				autoAdvance := utils.Ask("Start the next Pomodoro or break automatically, without asking? (y/N) ")
+				cfg.Pomodoro.AutoAdvance = strings.ToLower(autoAdvance) == "y"
+
+				if err := config.SaveConfig(cfg); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
 				fmt.Println("Pomodoro configuration saved.")
 				return nil
 			}
@@ -82,3 +190,39 @@ func PomodoroCommand() *cli.Command {
 		},
 	}
 }
+
+// pomodoroControlCommand returns a subcommand sending command to the background Pomodoro.
+func pomodoroControlCommand(command, usage string) *cli.Command {
+	return &cli.Command{
+		Name:  command,
+		Usage: usage,
+		Action: func(c *cli.Context) error {
+			status, err := pomodoro.Send(command)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if status.Stopped {
+				fmt.Println("Pomodoro stopped.")
+				return nil
+			}
+			printPomodoroStatus(status)
+			return nil
+		},
+	}
+}
+
+func printPomodoroStatus(status pomodoro.Status) {
+	if status.Phase == pomodoro.PhaseIdle {
+		fmt.Printf("Waiting to start the %s (tasky pomodoro resume).\n", status.Next.Label())
+	} else {
+		state := "running"
+		if status.Paused {
+			state = "paused"
+		}
+		fmt.Printf("%s %s: %s left of %s\n", status.Phase.Title(), state, pomodoro.FormatRemaining(status.Remaining), status.Duration)
+	}
+	fmt.Printf("%d/%d Pomodoros before a long break\n", status.CompletedInCycle, status.LongBreakInterval)
+	if status.TaskID != "" {
+		fmt.Printf("Task: %s %s (%s)\n", status.TaskID, status.TaskTitle, status.Project)
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"tasky/task"
+)
+
+// SetCommand returns a *cli.Command for the "set" command.
+func SetCommand() *cli.Command {
+	usage := "tasky set <task_id|issue_number|title> <field>=<value>..."
+	return &cli.Command{
+		Name:      "set",
+		Usage:     "Change fields of a task: " + strings.Join(task.SettableFields, ", "),
+		UsageText: usage,
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 2 {
+				return cli.Exit("Usage: "+usage, 1)
+			}
+			var fields []task.Field
+			for _, arg := range c.Args().Slice()[1:] {
+				f, err := task.ParseField(arg)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+				}
+				fields = append(fields, f)
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			svc := task.NewService(cfg)
+			updated, err := svc.Set(svc.Project(), c.Args().Get(0), fields)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+			fmt.Printf("Task '%s' updated.\n", updated.Title)
+			return nil
+		},
+	}
+}
@@ -1,9 +1,8 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
@@ -18,38 +17,88 @@ import (
 func StartCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "start",
-		Usage:     "Start development on a GitHub issue",
-		UsageText: "tasky start <issue_number>",
+		Usage:     "Start working on a task",
+		UsageText: "tasky start [--branch] [--type <type>] <task_id|issue_number|title>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "branch",
+				Aliases: []string{"b"},
+				Usage:   "Check out a branch for a task without issue too, named after branch.template",
+			},
+			&cli.StringFlag{
+				Name:    "type",
+				Aliases: []string{"t"},
+				Usage:   "Kind of work, {type} in branch.template (default branch.default_type)",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			if c.NArg() < 1 {
-				return cli.Exit("Usage: tasky start <issue_number>", 1)
+				return cli.Exit("Usage: tasky start <task_id|issue_number|title>", 1)
 			}
-			issueNumberStr := c.Args().Get(0)
-			issueNumber, err := strconv.Atoi(issueNumberStr)
+			taskRef := c.Args().Get(0)
+			cfg, err := loadConfig()
 			if err != nil {
-				return cli.Exit(fmt.Sprintf("Error: Invalid issue number '%s'. Please provide a valid integer.\n", issueNumberStr), 1)
+				return err
 			}
-			cfg := config.LoadConfig()
-			if err := task.StartTaskDevelopment(issueNumberStr); err != nil {
-				return cli.Exit(fmt.Sprintf("Error starting development: %v", err), 1)
+
+			svc := task.NewService(cfg)
+			projectName := svc.Project()
+			found, err := svc.Find(projectName, taskRef)
+			if err != nil {
+				// An issue without a note can still be developed on.
+				issueNumber, convErr := strconv.Atoi(strings.TrimPrefix(taskRef, "#"))
+				if !errors.Is(err, task.ErrNotFound) || convErr != nil {
+					return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+				}
+				if err := startDevelopment(svc, nil, issueNumber, c.String("type")); err != nil {
+					return err
+				}
+			} else {
+				if found.Issue != 0 || c.Bool("branch") {
+					if err := startDevelopment(svc, &found.Task, found.Issue, c.String("type")); err != nil {
+						return err
+					}
+				}
+				markInProgress(svc, projectName, &found.Task)
 			}
-			task.MarkTaskInProgress(cfg, issueNumber)
 			if err := utils.PlaySound(cfg.Sounds.Start); err != nil {
 				fmt.Printf("Warning: %v\n", err)
 			}
-			fmt.Printf("Task for issue #%s started.\n", issueNumberStr)
+			fmt.Printf("Task '%s' started.\n", taskRef)
 
-            // Ask to start a Pomodoro
-            fmt.Print("Start a Pomodoro? (Y/n): ")
-            reader := bufio.NewReader(os.Stdin)
-            input, _ := reader.ReadString('\n')
-            trimmedInput := strings.ToLower(strings.TrimSpace(input))
-
-            if trimmedInput == "y" || trimmedInput == "" {
-                pomodoro.StartPomodoroCycle(cfg)
-            }
-            return nil
+			// Ask to start a Pomodoro, which would block a script
+			if utils.Interactive() && utils.Confirm("Start a Pomodoro? (Y/n): ") {
+				pomodoro.StartPomodoroCycle(cfg)
+			}
+			return nil
 		},
 	}
 }
 
+// startDevelopment checks out the branch of a task, or of an issue without a note when
+// t is nil, telling the user about it.
+func startDevelopment(svc *task.Service, t *config.Task, issueNumber int, branchType string) error {
+	if issueNumber != 0 {
+		fmt.Printf("Starting development for issue #%d...\n", issueNumber)
+	}
+	branch, err := svc.StartDevelopment(t, issueNumber, branchType)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Error starting development: %v", err), 1)
+	}
+	fmt.Printf("Successfully started development on branch %s.\n", branch)
+	return nil
+}
+
+// markInProgress marks a task in progress, telling the user about it. Failing to is only
+// reported, as the work can start anyway.
+func markInProgress(svc *task.Service, projectName string, t *config.Task) {
+	_, err := svc.MarkInProgress(projectName, t.ID)
+	switch {
+	case errors.Is(err, task.ErrAlreadyInState):
+		fmt.Printf("Task '%s' is already marked as in-progress.\n", t.Title)
+	case err != nil:
+		fmt.Println("Error:", err)
+	default:
+		fmt.Printf("Task '%s' marked as in-progress.\n", t.Title)
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"tasky/tui"
+	"tasky/utils"
+)
+
+// UICommand returns a *cli.Command for the "ui" command.
+func UICommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ui",
+		Usage: "Open an interactive kanban board of the tasks",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "all",
+				Aliases: []string{"a"},
+				Usage:   "Show the tasks of every project",
+			},
+			&cli.StringFlag{
+				Name:    "project",
+				Aliases: []string{"p"},
+				Usage:   "Show the tasks of this project instead of the current one",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			projectName := ""
+			if !c.Bool("all") {
+				projectName = c.String("project")
+				if projectName == "" {
+					projectName = utils.GetProjectName(cfg)
+				}
+			}
+			if err := tui.Run(cfg, projectName); err != nil {
+				return cli.Exit(fmt.Sprintf("Error: %v", err), 1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultBranchPatterns find the issue of branches named as gh names them, "123-title".
+var DefaultBranchPatterns = []string{`^(?P<issue>[0-9]+)-`}
+
+// Defaults of the [branch] section.
+const (
+	DefaultBranchTemplate = "{issue}-{slug}"
+	DefaultBranchType     = "feature"
+)
+
+// branchPlaceholders are the placeholders of branch templates.
+var branchPlaceholders = []string{"type", "issue", "slug", "id"}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Branch is the [branch] section of the config: how the Git branches of tasks are named.
+//
+//	[branch]
+//	patterns = ['^(?:feature|fix)/PROJ-(?P<issue>[0-9]+)-', '^fix/(?P<issue>[0-9]+)_', '^(?P<issue>[0-9]+)/']
+//	template = "{type}/{issue}-{slug}"
+type Branch struct {
+	// Patterns are regular expressions finding the issue number in a branch name, tried in
+	// order; each captures it in a group named "issue". Default: DefaultBranchPatterns.
+	Patterns []string `toml:"patterns,omitempty"`
+	// Template names the branches tasky creates. {type} is the kind of work, {issue} the
+	// issue number, or the task ID without issue, {slug} the title in lowercase words
+	// joined by hyphens and {id} the task ID. Default: DefaultBranchTemplate.
+	Template string `toml:"template,omitempty"`
+	// DefaultType fills {type} when no type is given. Default: DefaultBranchType.
+	DefaultType string `toml:"default_type,omitempty"`
+}
+
+// IssuePatterns returns the patterns in use.
+func (b Branch) IssuePatterns() []string {
+	if len(b.Patterns) == 0 {
+		return DefaultBranchPatterns
+	}
+	return b.Patterns
+}
+
+// NameTemplate returns the template in use.
+func (b Branch) NameTemplate() string {
+	if b.Template == "" {
+		return DefaultBranchTemplate
+	}
+	return b.Template
+}
+
+// Type returns the type used when none is given.
+func (b Branch) Type() string {
+	if b.DefaultType == "" {
+		return DefaultBranchType
+	}
+	return b.DefaultType
+}
+
+// Validate checks that every pattern compiles and has an issue group, and that the
+// template only uses known placeholders.
+func (b Branch) Validate() error {
+	for i, pattern := range b.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return &SettingError{Key: "branch.patterns", Err: fmt.Errorf("pattern %d: %w", i+1, err)}
+		}
+		if re.SubexpIndex("issue") < 0 {
+			return &SettingError{Key: "branch.patterns", Err: fmt.Errorf("pattern %d '%s' has no group named issue, as in (?P<issue>[0-9]+)", i+1, pattern)}
+		}
+	}
+	return validateTemplate("branch.template", b.Template, branchPlaceholders)
+}
+
+// validateTemplate checks that template, the setting key, has a placeholder and only
+// known ones. An empty template is the default one.
+func validateTemplate(key, template string, placeholders []string) error {
+	if template != "" && !placeholderPattern.MatchString(template) {
+		return &SettingError{Key: key, Err: errors.New("the template has no placeholder")}
+	}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(placeholders, m[1]) {
+			return &SettingError{Key: key, Err: fmt.Errorf("unknown placeholder {%s} (expected {%s})", m[1], strings.Join(placeholders, "}, {"))}
+		}
+	}
+	return nil
+}
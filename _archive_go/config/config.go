@@ -1,7 +1,7 @@
 package config
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"os/user"
@@ -9,17 +9,36 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 type General struct {
 	VaultPath string `toml:"vault_path"`
+	// Vault selects a vault of Config.Vaults by name, or by path, instead of VaultPath.
+	Vault string `toml:"vault,omitempty"`
+	// Project names the project of the current directory, overriding ProjectNaming; it
+	// belongs in the .tasky.toml of a repository.
+	Project string `toml:"project,omitempty"`
+	// ProjectNaming names projects after their repository's remote: ProjectNamingRepo
+	// (the default) or ProjectNamingOwnerRepo, which tells apart same-named repositories.
+	ProjectNaming string `toml:"project_naming,omitempty"`
 }
 
+// Project naming schemes, see General.ProjectNaming.
+const (
+	ProjectNamingRepo      = "repo"
+	ProjectNamingOwnerRepo = "owner/repo"
+)
+
 type Pomodoro struct {
 	PomodoroDuration   int `toml:"pomodoro_duration,omitempty"`
 	ShortBreakDuration int `toml:"short_break_duration,omitempty"`
 	LongBreakDuration  int `toml:"long_break_duration,omitempty"`
 	LongBreakInterval  int `toml:"long_break_interval,omitempty"`
+	// AutoAdvance starts the next work session or break without asking.
+	AutoAdvance bool `toml:"auto_advance,omitempty"`
+	// SessionLog overrides where Pomodoro sessions are logged (default ~/.local/share/tasky/sessions.ndjson).
+	SessionLog string `toml:"session_log,omitempty"`
 }
 
 type Sounds struct {
@@ -28,13 +47,40 @@ type Sounds struct {
 	Done  string `toml:"done,omitempty"`
 }
 
+// Storage selects where task notes are persisted. Backend is "markdown" (the default,
+// notes inside the Obsidian vault) or "bolt" (a single bbolt database at Path). Layout places
+// the notes of the markdown backend in the vault, such as "Tasky/{project}"; see
+// Storage.LayoutTemplate.
+type Storage struct {
+	Backend string `toml:"backend,omitempty"`
+	Path    string `toml:"path,omitempty"`
+	Layout  string `toml:"layout,omitempty"`
+}
+
+// Forge selects the platform hosting the repositories, which is otherwise told from the
+// host of their origin remote. Kind is "github", "gitlab", "gitea" or "forgejo"; Hosts
+// gives the kind of self-hosted instances by host name.
+type Forge struct {
+	Kind  string            `toml:"kind,omitempty"`
+	Hosts map[string]string `toml:"hosts,omitempty"`
+}
+
 type Config struct {
 	General  General  `toml:"general"`
 	Pomodoro Pomodoro `toml:"pomodoro"`
 	Sounds   Sounds   `toml:"sounds"`
+	Storage  Storage  `toml:"storage"`
+	Forge    Forge    `toml:"forge"`
+	Branch   Branch   `toml:"branch"`
+	Filename Filename `toml:"filename"`
+	// NoteFormat customizes the frontmatter of notes.
+	NoteFormat NoteFormat `toml:"frontmatter"`
+	// Vaults names vault paths, to select them with general.vault or --vault.
+	Vaults map[string]string `toml:"vaults,omitempty"`
 }
 
 type Frontmatter struct {
+	ID            string `yaml:"id,omitempty"`
 	Title         string `yaml:"title"`
 	Status        string `yaml:"status"`
 	CreatedDate   string `yaml:"created_date"`
@@ -42,7 +88,27 @@ type Frontmatter struct {
 	StartDate     string `yaml:"start_date,omitempty"`
 	PomodoroCount int    `yaml:"pomodoro_count"`
 	Issue         int    `yaml:"issue,omitempty"`
-	Duration int    `yaml:"duration,omitempty"` // in minutes
+	Duration      int    `yaml:"duration,omitempty"` // in minutes
+	Tags          Tags   `yaml:"tags,omitempty"`
+	// Branch is the Git branch the task is developed on.
+	Branch string `yaml:"branch,omitempty"`
+}
+
+// Tags is the list of tags of a note. Obsidian accepts both a YAML list and a single
+// comma or space separated string, so both are decoded.
+type Tags []string
+
+func (t *Tags) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = strings.FieldsFunc(value.Value, func(r rune) bool { return r == ',' || r == ' ' })
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
 }
 
 const (
@@ -51,6 +117,12 @@ const (
 	StatusDone       = "done"
 )
 
+// Layouts of the dates stored in tasks. Notes may use other formats, see NoteFormat.
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02 15:04:05"
+)
+
 const (
 	SymbolTodo  = "☐"
 	SymbolDoing = "➜"
@@ -61,116 +133,49 @@ type Task struct {
 	Frontmatter `yaml:",inline"`
 }
 
-func getConfigPath() (string, error) {
+// homeDir returns the home directory of the current user, which holds the global config
+// file and which "~" stands for. Tests replace it.
+var homeDir = func() (string, error) {
 	usr, err := user.Current()
 	if err != nil {
 		return "", err
 	}
-	configDir := filepath.Join(usr.HomeDir, ".config", "tasky")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
-	}
-	return filepath.Join(configDir, "config.toml"), nil
+	return usr.HomeDir, nil
 }
 
-func LoadConfig() Config {
-	var cfg Config
-	configPath, err := getConfigPath()
+// ConfigPath returns the path of the global config file.
+func ConfigPath() (string, error) {
+	home, err := homeDir()
 	if err != nil {
-		panic("Error getting config path: " + err.Error())
-	}
-
-	// Set initial default Pomodoro values
-	cfg.Pomodoro.PomodoroDuration = 25
-	cfg.Pomodoro.ShortBreakDuration = 5
-	cfg.Pomodoro.LongBreakDuration = 15
-	cfg.Pomodoro.LongBreakInterval = 4
-
-	shouldSaveConfig := false // Flag to track if we need to save the config
-
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Config file does not exist, this is the first run
-		fmt.Print("Welcome to Tasky! Press Enter to use the default path (~/Documents/Obsidian) or enter a custom path: ")
-		reader := bufio.NewReader(os.Stdin)
-		newPath, _ := reader.ReadString('\n')
-		newPath = strings.TrimSpace(newPath)
-
-		usr, err := user.Current()
-		if err != nil {
-			panic("Error getting current user: " + err.Error())
-		}
-		homeDir := usr.HomeDir
-
-		if newPath == "" {
-			newPath = filepath.Join(homeDir, "Documents", "Obsidian")
-		} else if strings.HasPrefix(newPath, "~/") {
-			newPath = filepath.Join(homeDir, newPath[2:])
-		}
-
-		absPath, err := filepath.Abs(newPath)
-		if err != nil {
-			panic("Error converting to absolute path: " + err.Error())
-		}
-
-		cfg.General.VaultPath = absPath
-		shouldSaveConfig = true // New config, so save it
-		fmt.Println("Configuration saved.")
-	} else {
-		// Config file exists, load it
-		var loadedCfg Config
-		if _, err := toml.DecodeFile(configPath, &loadedCfg); err != nil {
-			panic("Error loading config.toml: " + err.Error())
-		}
-
-		// Overwrite defaults with loaded values
-		cfg.General.VaultPath = loadedCfg.General.VaultPath
-		cfg.Sounds = loadedCfg.Sounds
-
-		// For Pomodoro settings, if the loaded value is 0, it means it was missing or explicitly 0 in the file.
-		// In this case, we keep our default. If it's non-zero, we use the loaded value.
-		// And if we use our default, we should save the config back.
-		if loadedCfg.Pomodoro.PomodoroDuration != 0 {
-			cfg.Pomodoro.PomodoroDuration = loadedCfg.Pomodoro.PomodoroDuration
-		} else {
-			shouldSaveConfig = true // Default was used, so save
-		}
-		if loadedCfg.Pomodoro.ShortBreakDuration != 0 {
-			cfg.Pomodoro.ShortBreakDuration = loadedCfg.Pomodoro.ShortBreakDuration
-		} else {
-			shouldSaveConfig = true // Default was used, so save
-		}
-		if loadedCfg.Pomodoro.LongBreakDuration != 0 {
-			cfg.Pomodoro.LongBreakDuration = loadedCfg.Pomodoro.LongBreakDuration
-		} else {
-			shouldSaveConfig = true // Default was used, so save
-		}
-		if loadedCfg.Pomodoro.LongBreakInterval != 0 {
-			cfg.Pomodoro.LongBreakInterval = loadedCfg.Pomodoro.LongBreakInterval
-		} else {
-			shouldSaveConfig = true // Default was used, so save
-		}
-	}
-
-	if shouldSaveConfig {
-		SaveConfig(cfg)
+		return "", err
 	}
+	return filepath.Join(home, ".config", "tasky", "config.toml"), nil
+}
 
-	return cfg
+// LoadConfig returns the effective configuration, see load for its layers. It never
+// prompts: before the first "tasky init" it returns ErrNotConfigured, and an invalid
+// setting is reported as a *SettingError.
+func LoadConfig() (Config, error) {
+	cfg, _, err := LoadConfigWithOrigins()
+	return cfg, err
 }
 
-func SaveConfig(cfg Config) {
-	configPath, err := getConfigPath()
+// SaveConfig writes cfg to the global config file. Give it a configuration from
+// LoadGlobalConfig so that the project config, environment and flags are not saved.
+func SaveConfig(cfg Config) error {
+	configPath, err := ConfigPath()
 	if err != nil {
-		panic("Error getting config path: " + err.Error())
+		return fmt.Errorf("error getting config path: %w", err)
 	}
-
-	f, err := os.Create(configPath)
-	if err != nil {
-		panic("Error creating config.toml: " + err.Error())
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		return fmt.Errorf("error encoding config to TOML: %w", err)
 	}
-	defer f.Close()
-
-	if err := toml.NewEncoder(f).Encode(cfg); err != nil {
-		panic("Error encoding config to TOML: " + err.Error())
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", configPath, err)
 	}
+	return nil
 }
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// Defaults of the [filename] section.
+const (
+	DefaultFilenameTemplate = "{issue}-{slug}"
+	DefaultSlugLength       = 50
+)
+
+// filenamePlaceholders are the placeholders of filename templates.
+var filenamePlaceholders = []string{"issue", "slug", "date", "id"}
+
+// Filename is the [filename] section of the config: how new task notes are named.
+//
+//	[filename]
+//	template = "{date}-{slug}"
+//	max_length = 30
+type Filename struct {
+	// Template names new notes, without the .md extension. {slug} is the title in
+	// lowercase ASCII words joined by hyphens, {issue} the issue number, left out with its
+	// separator without issue, {date} the creation date as 2006-01-02 and {id} the task
+	// ID. Default: DefaultFilenameTemplate.
+	Template string `toml:"template,omitempty"`
+	// MaxLength caps the length of {slug}. Default: DefaultSlugLength.
+	MaxLength int `toml:"max_length,omitempty"`
+}
+
+// NameTemplate returns the template in use.
+func (f Filename) NameTemplate() string {
+	if f.Template == "" {
+		return DefaultFilenameTemplate
+	}
+	return f.Template
+}
+
+// SlugLength returns the maximum length of slugs.
+func (f Filename) SlugLength() int {
+	if f.MaxLength == 0 {
+		return DefaultSlugLength
+	}
+	return f.MaxLength
+}
+
+// Validate checks that the template only uses known placeholders and names a file of
+// the project's directory, and that the maximum length is positive.
+func (f Filename) Validate() error {
+	if err := validateTemplate("filename.template", f.Template, filenamePlaceholders); err != nil {
+		return err
+	}
+	if strings.ContainsAny(f.Template, `/\`) {
+		return &SettingError{Key: "filename.template", Err: errors.New("the template must not contain a path separator")}
+	}
+	if f.MaxLength < 0 {
+		return &SettingError{Key: "filename.max_length", Err: errors.New("must not be negative")}
+	}
+	return nil
+}
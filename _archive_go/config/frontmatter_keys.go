@@ -1,6 +1,16 @@
 package config
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FrontmatterKeys maps every task field to its default frontmatter key. The
+// [frontmatter.keys] section of the config can rename them.
 var FrontmatterKeys = map[string]string{
+	"id":             "id",
 	"title":          "title",
 	"status":         "status",
 	"created_date":   "created_date",
@@ -8,4 +18,107 @@ var FrontmatterKeys = map[string]string{
 	"start_date":     "start_date",
 	"pomodoro_count": "pomodoro_count",
 	"issue":          "issue",
+	"duration":       "duration",
+	"tags":           "tags",
+	"branch":         "branch",
+}
+
+// NoteFormat is the [frontmatter] section of the config: how tasks are written in the
+// frontmatter of notes, for vaults whose other plugins expect different keys or values.
+// Tasks themselves always use the default statuses and the DateLayout/DateTimeLayout
+// formats; notes are converted when read and written.
+//
+//	[frontmatter]
+//	date_format = "02/01/2006"
+//	datetime_format = "02/01/2006 15:04"
+//	[frontmatter.keys]
+//	status = "state"
+//	created_date = "created"
+//	done_date = "completed"
+//	[frontmatter.status]
+//	in_progress = "doing"
+type NoteFormat struct {
+	// Keys maps task fields (keys of FrontmatterKeys) to the frontmatter key used instead.
+	Keys map[string]string `toml:"keys,omitempty"`
+	// Status holds the values written for each status.
+	Status StatusValues `toml:"status,omitempty"`
+	// DateFormat and DateTimeFormat are Go time layouts for dates with and without a time.
+	DateFormat     string `toml:"date_format,omitempty"`
+	DateTimeFormat string `toml:"datetime_format,omitempty"`
+}
+
+// StatusValues renames the statuses in notes. Empty values keep the default.
+type StatusValues struct {
+	Todo       string `toml:"todo,omitempty"`
+	InProgress string `toml:"in_progress,omitempty"`
+	Done       string `toml:"done,omitempty"`
+}
+
+// Key returns the frontmatter key of a task field.
+func (f NoteFormat) Key(field string) string {
+	if key := f.Keys[field]; key != "" {
+		return key
+	}
+	return FrontmatterKeys[field]
+}
+
+// StatusValue returns the value written in notes for a status.
+func (f NoteFormat) StatusValue(status string) string {
+	var custom string
+	switch status {
+	case StatusTodo:
+		custom = f.Status.Todo
+	case StatusInProgress:
+		custom = f.Status.InProgress
+	case StatusDone:
+		custom = f.Status.Done
+	}
+	if custom == "" {
+		return status
+	}
+	return custom
+}
+
+// Validate checks that every renamed field exists, that no two fields share a key or a
+// status value, and that the date formats are time layouts.
+func (f NoteFormat) Validate() error {
+	fields := make([]string, 0, len(FrontmatterKeys))
+	for field := range FrontmatterKeys {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for field := range f.Keys {
+		if _, ok := FrontmatterKeys[field]; !ok {
+			return &SettingError{Key: "frontmatter.keys." + field, Err: fmt.Errorf("unknown field '%s' (expected one of %s)", field, strings.Join(fields, ", "))}
+		}
+	}
+	usedBy := make(map[string]string)
+	for _, field := range fields {
+		key := f.Key(field)
+		if other, ok := usedBy[key]; ok {
+			return &SettingError{Key: "frontmatter.keys." + field, Err: fmt.Errorf("'%s' and '%s' both use the key '%s'", other, field, key)}
+		}
+		usedBy[key] = field
+	}
+	values := make(map[string]bool)
+	for _, status := range []string{StatusTodo, StatusInProgress, StatusDone} {
+		value := strings.ToLower(f.StatusValue(status))
+		if values[value] {
+			key := "frontmatter.status." + strings.ReplaceAll(status, " ", "_")
+			return &SettingError{Key: key, Err: fmt.Errorf("the value '%s' is used for two statuses", value)}
+		}
+		values[value] = true
+	}
+	for name, layout := range map[string]string{"date_format": f.DateFormat, "datetime_format": f.DateTimeFormat} {
+		if layout == "" {
+			continue
+		}
+		sample := time.Date(2009, time.November, 17, 20, 34, 58, 0, time.Local)
+		if formatted := sample.Format(layout); formatted == layout {
+			return &SettingError{Key: "frontmatter." + name, Err: fmt.Errorf("'%s' is not a Go time layout such as '%s'", layout, DateLayout)}
+		} else if _, err := time.ParseInLocation(layout, formatted, time.Local); err != nil {
+			return &SettingError{Key: "frontmatter." + name, Err: err}
+		}
+	}
+	return nil
 }
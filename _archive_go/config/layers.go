@@ -0,0 +1,456 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectConfigFile is the per-repository config file, looked up in the current directory
+// and its parents. It takes the same settings as the global config.toml.
+const ProjectConfigFile = ".tasky.toml"
+
+// EnvPrefix starts the environment variables overriding settings: the key in upper case
+// with dots replaced by underscores, such as TASKY_POMODORO_AUTO_ADVANCE for
+// pomodoro.auto_advance. TASKY_VAULT is short for TASKY_GENERAL_VAULT.
+const EnvPrefix = "TASKY_"
+
+// OriginDefault is the origin of a setting that no layer sets.
+const OriginDefault = "default"
+
+// Origins maps every setting, by its dotted key such as "pomodoro.auto_advance", to where
+// its effective value comes from: OriginDefault, a config file, an environment variable
+// or a command line flag.
+type Origins map[string]string
+
+// override is a setting given on the command line, see SetOverride.
+type override struct {
+	key, value, origin string
+}
+
+var overrides []override
+
+// SetOverride sets the setting key to value above every other layer; it is meant for
+// command line flags, origin naming the flag.
+func SetOverride(key, value, origin string) {
+	overrides = append(overrides, override{key, value, origin})
+}
+
+// OverrideEnv returns the overrides as environment variables, so that a tasky process
+// started by this one uses the same settings.
+func OverrideEnv() []string {
+	var env []string
+	for _, o := range overrides {
+		env = append(env, envName(o.key)+"="+o.value)
+	}
+	return env
+}
+
+// pathSettings are the settings holding a path, which is relative to the config file
+// setting it. Vaults are paths too.
+var pathSettings = []string{"general.vault_path", "pomodoro.session_log", "sounds.break", "sounds.start", "sounds.done", "storage.path"}
+
+func defaultConfig() Config {
+	var cfg Config
+	cfg.Pomodoro.PomodoroDuration = 25
+	cfg.Pomodoro.ShortBreakDuration = 5
+	cfg.Pomodoro.LongBreakDuration = 15
+	cfg.Pomodoro.LongBreakInterval = 4
+	return cfg
+}
+
+// ErrNotConfigured is returned when there is no global config file and no other layer
+// sets the vault, before "tasky init" was run.
+var ErrNotConfigured = errors.New("tasky is not configured yet: run 'tasky init', or select a vault with --vault or TASKY_GENERAL_VAULT_PATH")
+
+// LoadConfigWithOrigins is LoadConfig also returning where each setting comes from.
+func LoadConfigWithOrigins() (Config, Origins, error) {
+	return load(false)
+}
+
+// LoadGlobalConfig loads the global config file alone, without the project config,
+// environment variables or flags, for changing and saving it.
+func LoadGlobalConfig() (Config, error) {
+	cfg, _, err := load(true)
+	return cfg, err
+}
+
+// load builds the configuration from its layers, each overriding the previous ones: the
+// built-in defaults, the global config file, the project config file, TASKY_*
+// environment variables and command line flags. With globalOnly, it stops after the
+// global config file. It only reads: missing settings get their default in the returned
+// Config, and "tasky init" is what writes them to the file.
+func load(globalOnly bool) (Config, Origins, error) {
+	cfg := defaultConfig()
+	origins := make(Origins)
+	walkSettings(reflect.ValueOf(&cfg).Elem(), "", func(key string, _ reflect.Value, _ bool) {
+		origins[key] = OriginDefault
+	})
+
+	configPath, err := ConfigPath()
+	if err != nil {
+		return cfg, origins, fmt.Errorf("error getting config path: %w", err)
+	}
+	_, found, err := decodeLayer(configPath, &cfg, origins)
+	if err != nil {
+		return cfg, origins, err
+	}
+
+	if !globalOnly {
+		if projectPath, ok := findProjectConfig(); ok {
+			if _, _, err := decodeLayer(projectPath, &cfg, origins); err != nil {
+				return cfg, origins, err
+			}
+		}
+		if err := applyEnv(&cfg, origins); err != nil {
+			return cfg, origins, err
+		}
+		for _, o := range overrides {
+			if err := setSetting(&cfg, o.key, o.value); err != nil {
+				return cfg, origins, &SettingError{Key: o.key, Origin: o.origin, Err: err}
+			}
+			origins[o.key] = o.origin
+			replaceVaultName(&cfg, origins, o.key == "general.vault_path", o.origin)
+		}
+		if err := resolveVault(&cfg, origins); err != nil {
+			return cfg, origins, err
+		}
+		if !found && cfg.General.VaultPath == "" {
+			return cfg, origins, ErrNotConfigured
+		}
+	}
+
+	if err := validate(cfg); err != nil {
+		return cfg, origins, locate(err, origins)
+	}
+	return withDefaultDurations(cfg, origins), origins, nil
+}
+
+// Init creates the global config file with the default settings and the vault at
+// vaultPath, relative to the current directory. An existing file is only replaced with
+// overwrite. It returns the path of the file.
+func Init(vaultPath string, overwrite bool) (string, error) {
+	configPath, err := ConfigPath()
+	if err != nil {
+		return "", fmt.Errorf("error getting config path: %w", err)
+	}
+	if _, err := os.Stat(configPath); err == nil && !overwrite {
+		return configPath, fmt.Errorf("%s already exists", configPath)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return configPath, err
+	}
+	cfg := defaultConfig()
+	cfg.General.VaultPath = resolvePath(vaultPath, cwd)
+	return configPath, SaveConfig(cfg)
+}
+
+// decodeLayer decodes the config file at path over cfg and records the settings it sets
+// in origins. It reports whether the file exists.
+func decodeLayer(path string, cfg *Config, origins Origins) (toml.MetaData, bool, error) {
+	md, err := toml.DecodeFile(path, cfg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return md, false, nil
+	}
+	if err != nil {
+		return md, true, decodeError(path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		key := undecoded[0].String()
+		return md, true, &SettingError{Key: key, Origin: path, Line: keyLine(path, key), Err: errors.New("unknown setting")}
+	}
+	dir := filepath.Dir(path)
+	for _, key := range md.Keys() {
+		if md.Type(key...) == "Hash" {
+			continue
+		}
+		origins[strings.Join(key, ".")] = path
+		if len(key) == 2 && key[0] == "vaults" {
+			cfg.Vaults[key[1]] = resolvePath(cfg.Vaults[key[1]], dir)
+		} else if slices.Contains(pathSettings, key.String()) {
+			field, _ := settingField(cfg, key.String())
+			field.SetString(resolvePath(field.String(), dir))
+		}
+	}
+	replaceVaultName(cfg, origins, md.IsDefined("general", "vault_path") && !md.IsDefined("general", "vault"), path)
+	return md, true, nil
+}
+
+// replaceVaultName forgets the vault name of a lower layer when a layer, origin, sets the
+// vault path directly.
+func replaceVaultName(cfg *Config, origins Origins, setsPath bool, origin string) {
+	if setsPath && cfg.General.Vault != "" {
+		cfg.General.Vault = ""
+		origins["general.vault"] = origin
+	}
+}
+
+// findProjectConfig returns the ProjectConfigFile of the current directory or of its
+// closest parent having one.
+func findProjectConfig() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// applyEnv sets the settings given by environment variables, see EnvPrefix. Paths are
+// resolved as in config files, relative to the current directory.
+func applyEnv(cfg *Config, origins Origins) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	walkSettings(reflect.ValueOf(cfg).Elem(), "", func(key string, field reflect.Value, mapEntry bool) {
+		name := envName(key)
+		value, ok := os.LookupEnv(name)
+		if mapEntry || !ok || err != nil {
+			return
+		}
+		if err = parseSetting(field, value); err != nil {
+			err = &SettingError{Key: key, Origin: "env " + name, Err: err}
+			return
+		}
+		if slices.Contains(pathSettings, key) {
+			field.SetString(resolvePath(value, cwd))
+		}
+		origins[key] = "env " + name
+		replaceVaultName(cfg, origins, key == "general.vault_path", "env "+name)
+	})
+	if err != nil {
+		return err
+	}
+	if value, ok := os.LookupEnv(EnvPrefix + "VAULT"); ok {
+		cfg.General.Vault = value
+		origins["general.vault"] = "env " + EnvPrefix + "VAULT"
+	}
+	return nil
+}
+
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// resolveVault sets the vault path from general.vault, the name of a vault of the
+// [vaults] table or a path.
+func resolveVault(cfg *Config, origins Origins) error {
+	name := cfg.General.Vault
+	if name == "" {
+		return nil
+	}
+	path, ok := cfg.Vaults[name]
+	if !ok {
+		if !strings.ContainsRune(name, filepath.Separator) && !strings.HasPrefix(name, "~") {
+			names := make([]string, 0, len(cfg.Vaults))
+			for n := range cfg.Vaults {
+				names = append(names, n)
+			}
+			slices.Sort(names)
+			return &SettingError{Key: "general.vault", Origin: origins["general.vault"],
+				Err: fmt.Errorf("unknown vault '%s' (configured vaults: %s)", name, strings.Join(names, ", "))}
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		path = resolvePath(name, cwd)
+	}
+	cfg.General.VaultPath = path
+	origins["general.vault_path"] = fmt.Sprintf("vault '%s' from %s", name, origins["general.vault"])
+	return nil
+}
+
+// withDefaultDurations replaces Pomodoro durations that are missing, or 0, with their
+// defaults, marking them as such in origins if it is not nil.
+func withDefaultDurations(cfg Config, origins Origins) Config {
+	defaults := defaultConfig()
+	for _, s := range []struct {
+		key      string
+		value    *int
+		fallback int
+	}{
+		{"pomodoro.pomodoro_duration", &cfg.Pomodoro.PomodoroDuration, defaults.Pomodoro.PomodoroDuration},
+		{"pomodoro.short_break_duration", &cfg.Pomodoro.ShortBreakDuration, defaults.Pomodoro.ShortBreakDuration},
+		{"pomodoro.long_break_duration", &cfg.Pomodoro.LongBreakDuration, defaults.Pomodoro.LongBreakDuration},
+		{"pomodoro.long_break_interval", &cfg.Pomodoro.LongBreakInterval, defaults.Pomodoro.LongBreakInterval},
+	} {
+		if *s.value == 0 {
+			*s.value = s.fallback
+			if origins != nil {
+				origins[s.key] = OriginDefault
+			}
+		}
+	}
+	return cfg
+}
+
+// resolvePath expands a leading "~" of path and makes it absolute, relative to dir.
+func resolvePath(path, dir string) string {
+	if path == "" {
+		return path
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := homeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Settings returns the effective value of every setting of cfg, by key, in the order of
+// the config file, formatted as in TOML.
+func Settings(cfg Config) (keys []string, values map[string]string) {
+	values = make(map[string]string)
+	walkSettings(reflect.ValueOf(&cfg).Elem(), "", func(key string, field reflect.Value, _ bool) {
+		keys = append(keys, key)
+		switch field.Kind() {
+		case reflect.String:
+			values[key] = strconv.Quote(field.String())
+		case reflect.Slice:
+			items := make([]string, field.Len())
+			for i := range items {
+				items[i] = strconv.Quote(fmt.Sprint(field.Index(i).Interface()))
+			}
+			values[key] = "[" + strings.Join(items, ", ") + "]"
+		default:
+			values[key] = fmt.Sprint(field.Interface())
+		}
+	})
+	return keys, values
+}
+
+// walkSettings calls fn with the key and value of every setting of v, a configuration
+// struct, in field order. The entries of a map are settings of their own, in key order.
+func walkSettings(v reflect.Value, prefix string, fn func(key string, field reflect.Value, mapEntry bool)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := tomlName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			walkSettings(field, key+".", fn)
+		case reflect.Map:
+			names := make([]string, 0, field.Len())
+			for _, k := range field.MapKeys() {
+				names = append(names, k.String())
+			}
+			slices.Sort(names)
+			for _, n := range names {
+				fn(key+"."+n, field.MapIndex(reflect.ValueOf(n)), true)
+			}
+		default:
+			fn(key, field, false)
+		}
+	}
+}
+
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// settingField returns the field of cfg holding the setting key, which must not be a map
+// entry.
+func settingField(cfg *Config, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return v, errors.New("unknown setting")
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if tomlName(v.Type().Field(i)) == part {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return v, errors.New("unknown setting")
+		}
+	}
+	return v, nil
+}
+
+// setSetting sets the setting key of cfg, which may be a map entry such as
+// "vaults.work", from its textual value.
+func setSetting(cfg *Config, key, value string) error {
+	if table, entry, ok := cutLast(key); ok {
+		if m, err := settingField(cfg, table); err == nil && m.Kind() == reflect.Map {
+			if m.IsNil() {
+				m.Set(reflect.MakeMap(m.Type()))
+			}
+			elem := reflect.New(m.Type().Elem()).Elem()
+			if err := parseSetting(elem, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(entry), elem)
+			return nil
+		}
+	}
+	field, err := settingField(cfg, key)
+	if err != nil {
+		return err
+	}
+	return parseSetting(field, value)
+}
+
+func cutLast(key string) (string, string, bool) {
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
+
+// parseSetting sets field from its textual value.
+func parseSetting(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("'%s' is not true or false", value)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("cannot set a %s", field.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupLayers writes the global config file of a temporary home directory and the
+// ProjectConfigFile of a repository, the current directory of the test. An empty content
+// leaves the file out.
+func setupLayers(t *testing.T, global, project string) (globalPath, projectPath string) {
+	t.Helper()
+	home, repo := t.TempDir(), t.TempDir()
+	saved := homeDir
+	homeDir = func() (string, error) { return home, nil }
+	t.Cleanup(func() {
+		homeDir = saved
+		overrides = nil
+	})
+	globalPath = filepath.Join(home, ".config", "tasky", "config.toml")
+	projectPath = filepath.Join(repo, ProjectConfigFile)
+	for path, content := range map[string]string{globalPath: global, projectPath: project} {
+		if content == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(repo)
+	return globalPath, projectPath
+}
+
+func TestLoadLayers(t *testing.T) {
+	globalPath, projectPath := setupLayers(t, `[general]
+vault_path = "vault"
+
+[pomodoro]
+pomodoro_duration = 30
+short_break_duration = 6
+long_break_duration = 20
+`, `[pomodoro]
+short_break_duration = 7
+long_break_duration = 25
+`)
+	t.Setenv("TASKY_POMODORO_LONG_BREAK_DURATION", "30")
+	t.Setenv("TASKY_POMODORO_AUTO_ADVANCE", "true")
+	SetOverride("pomodoro.auto_advance", "false", "--no-auto flag")
+
+	cfg, origins, err := LoadConfigWithOrigins()
+	if err != nil {
+		t.Fatalf("LoadConfigWithOrigins: %v", err)
+	}
+	tests := []struct {
+		key    string
+		value  any
+		origin string
+	}{
+		{"general.vault_path", cfg.General.VaultPath, globalPath},
+		{"pomodoro.pomodoro_duration", cfg.Pomodoro.PomodoroDuration, globalPath},
+		{"pomodoro.short_break_duration", cfg.Pomodoro.ShortBreakDuration, projectPath},
+		{"pomodoro.long_break_duration", cfg.Pomodoro.LongBreakDuration, "env TASKY_POMODORO_LONG_BREAK_DURATION"},
+		{"pomodoro.auto_advance", cfg.Pomodoro.AutoAdvance, "--no-auto flag"},
+		{"pomodoro.long_break_interval", cfg.Pomodoro.LongBreakInterval, OriginDefault},
+	}
+	want := map[string]any{
+		"general.vault_path":            filepath.Join(filepath.Dir(globalPath), "vault"),
+		"pomodoro.pomodoro_duration":    30,
+		"pomodoro.short_break_duration": 7,
+		"pomodoro.long_break_duration":  30,
+		"pomodoro.auto_advance":         false,
+		"pomodoro.long_break_interval":  4,
+	}
+	for _, tt := range tests {
+		if tt.value != want[tt.key] {
+			t.Errorf("%s = %v, want %v", tt.key, tt.value, want[tt.key])
+		}
+		if origins[tt.key] != tt.origin {
+			t.Errorf("origin of %s = %q, want %q", tt.key, origins[tt.key], tt.origin)
+		}
+	}
+
+	// The global config alone ignores the other layers.
+	global, err := LoadGlobalConfig()
+	if err != nil {
+		t.Fatalf("LoadGlobalConfig: %v", err)
+	}
+	if global.Pomodoro.ShortBreakDuration != 6 || global.Pomodoro.LongBreakDuration != 20 || global.Pomodoro.AutoAdvance {
+		t.Errorf("LoadGlobalConfig applied other layers: %+v", global.Pomodoro)
+	}
+}
+
+func TestLoadProjectConfigPaths(t *testing.T) {
+	globalPath, projectPath := setupLayers(t, `[general]
+vault_path = "/vaults/main"
+
+[vaults]
+work = "/vaults/work"
+`, `[general]
+vault = "work"
+
+[pomodoro]
+session_log = "sessions.ndjson"
+`)
+	cfg, origins, err := LoadConfigWithOrigins()
+	if err != nil {
+		t.Fatalf("LoadConfigWithOrigins: %v", err)
+	}
+	if cfg.General.VaultPath != "/vaults/work" {
+		t.Errorf("vault path = %q, want the work vault", cfg.General.VaultPath)
+	}
+	if want := "vault 'work' from " + projectPath; origins["general.vault_path"] != want {
+		t.Errorf("origin of the vault path = %q, want %q", origins["general.vault_path"], want)
+	}
+	if origins["vaults.work"] != globalPath {
+		t.Errorf("origin of vaults.work = %q, want %q", origins["vaults.work"], globalPath)
+	}
+	if want := filepath.Join(filepath.Dir(projectPath), "sessions.ndjson"); cfg.Pomodoro.SessionLog != want {
+		t.Errorf("session log = %q, want %q relative to the project config", cfg.Pomodoro.SessionLog, want)
+	}
+}
+
+func TestLoadNotConfigured(t *testing.T) {
+	setupLayers(t, "", "")
+	if _, err := LoadConfig(); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("LoadConfig: got %v, want ErrNotConfigured", err)
+	}
+	t.Setenv("TASKY_GENERAL_VAULT_PATH", "/vault")
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig with a vault from the environment: %v", err)
+	}
+}
+
+func TestLoadSettingErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		global, project string
+		env             map[string]string
+		key             string
+		origin          string // "global" or "project" for the config files
+		line            int
+	}{
+		{
+			name:   "validation in the global file",
+			global: "[general]\nvault_path = \"/vault\"\n\n[pomodoro]\npomodoro_duration = 25\nlong_break_interval = -1\n",
+			key:    "pomodoro.long_break_interval", origin: "global", line: 6,
+		},
+		{
+			name:    "validation in the project file",
+			global:  "[general]\nvault_path = \"/vault\"\n",
+			project: "# Repository settings\n[general]\nproject_naming = \"nope\"\n",
+			key:     "general.project_naming", origin: "project", line: 3,
+		},
+		{
+			name:    "dotted key",
+			global:  "general.vault_path = \"/vault\"\n",
+			project: "\npomodoro.short_break_duration = -5\n",
+			key:     "pomodoro.short_break_duration", origin: "project", line: 2,
+		},
+		{
+			name:   "unknown setting",
+			global: "[general]\nvault_path = \"/vault\"\n\n[pomodoro]\nduration = 25\n",
+			key:    "pomodoro.duration", origin: "global", line: 5,
+		},
+		{
+			name:   "wrong type",
+			global: "[general]\nvault_path = \"/vault\"\n[pomodoro]\nauto_advance = \"yes\"\n",
+			key:    "pomodoro.auto_advance", origin: "global", line: 4,
+		},
+		{
+			name:   "environment variable",
+			global: "[general]\nvault_path = \"/vault\"\n",
+			env:    map[string]string{"TASKY_POMODORO_POMODORO_DURATION": "soon"},
+			key:    "pomodoro.pomodoro_duration", origin: "env TASKY_POMODORO_POMODORO_DURATION",
+		},
+		{
+			name:   "validation of an environment variable",
+			global: "[general]\nvault_path = \"/vault\"\n",
+			env:    map[string]string{"TASKY_POMODORO_SHORT_BREAK_DURATION": "-1"},
+			key:    "pomodoro.short_break_duration", origin: "env TASKY_POMODORO_SHORT_BREAK_DURATION",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globalPath, projectPath := setupLayers(t, tt.global, tt.project)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			origin := map[string]string{"global": globalPath, "project": projectPath}[tt.origin]
+			if origin == "" {
+				origin = tt.origin
+			}
+
+			_, err := LoadConfig()
+			var settingErr *SettingError
+			if !errors.As(err, &settingErr) {
+				t.Fatalf("LoadConfig: got %v, want a *SettingError", err)
+			}
+			if settingErr.Key != tt.key || settingErr.Origin != origin || settingErr.Line != tt.line {
+				t.Errorf("got %s at %s:%d, want %s at %s:%d", settingErr.Key, settingErr.Origin, settingErr.Line, tt.key, origin, tt.line)
+			}
+		})
+	}
+}
+
+func TestLoadEnvPaths(t *testing.T) {
+	setupLayers(t, "", "")
+	home, _ := homeDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		env, value string
+		path       func(Config) string
+		want       string
+	}{
+		{"TASKY_GENERAL_VAULT_PATH", "~/vault", func(c Config) string { return c.General.VaultPath }, filepath.Join(home, "vault")},
+		{"TASKY_GENERAL_VAULT_PATH", "notes/../vault", func(c Config) string { return c.General.VaultPath }, filepath.Join(cwd, "vault")},
+		{"TASKY_VAULT", "~", func(c Config) string { return c.General.VaultPath }, home},
+		{"TASKY_VAULT", "~/work", func(c Config) string { return c.General.VaultPath }, filepath.Join(home, "work")},
+		{"TASKY_POMODORO_SESSION_LOG", "~/sessions.ndjson", func(c Config) string { return c.Pomodoro.SessionLog }, filepath.Join(home, "sessions.ndjson")},
+		{"TASKY_STORAGE_PATH", "tasks.db", func(c Config) string { return c.Storage.Path }, filepath.Join(cwd, "tasks.db")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv("TASKY_GENERAL_VAULT_PATH", "/vault")
+			t.Setenv(tt.env, tt.value)
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if got := tt.path(cfg); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
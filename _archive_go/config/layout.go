@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultLayout keeps the notes of a project in a Tasky directory of the project's
+// directory of the vault.
+const DefaultLayout = "{project}/Tasky"
+
+// layoutPlaceholder is the placeholder of layouts, replaced by the project name.
+const layoutPlaceholder = "{project}"
+
+// LayoutTemplate returns the layout in use: where the notes of a project are kept,
+// relative to the vault. Default: DefaultLayout.
+func (s Storage) LayoutTemplate() string {
+	if s.Layout == "" {
+		return DefaultLayout
+	}
+	return s.Layout
+}
+
+// SplitLayout returns the parts of a layout around {project}: the directories leading
+// to the project's directory, each followed by a slash, and those leading from it to the
+// notes, each preceded by one.
+func SplitLayout(layout string) (string, string) {
+	prefix, suffix, _ := strings.Cut(layout, layoutPlaceholder)
+	return prefix, suffix
+}
+
+// LayoutDir returns the directory holding the notes of projectName in vault according
+// to layout.
+func LayoutDir(vault, layout, projectName string) string {
+	return filepath.Join(vault, filepath.FromSlash(strings.Replace(layout, layoutPlaceholder, projectName, 1)))
+}
+
+// ValidateLayout checks that layout is a relative slash-separated path inside the vault
+// with {project} as one of its directories, exactly once.
+func ValidateLayout(layout string) error {
+	if strings.Count(layout, layoutPlaceholder) != 1 {
+		return errors.New("the layout must contain {project} once")
+	}
+	if m := placeholderPattern.FindAllString(layout, -1); len(m) != 1 {
+		return errors.New("{project} is the only placeholder of layouts")
+	}
+	if path.IsAbs(layout) || strings.Contains(layout, `\`) || path.Clean(layout) != layout {
+		return errors.New("the layout must be a clean relative path separated by slashes")
+	}
+	for _, dir := range strings.Split(layout, "/") {
+		if dir == ".." || dir != layoutPlaceholder && strings.Contains(dir, layoutPlaceholder) {
+			return errors.New("{project} must be a whole directory of the layout, inside the vault")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SettingError is an invalid setting. Origin tells where its value comes from, see
+// Origins, and Line its line when that is a config file.
+type SettingError struct {
+	Key    string
+	Origin string
+	Line   int
+	Err    error
+}
+
+func (e *SettingError) Error() string {
+	switch {
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %v", e.Origin, e.Line, e.Key, e.Err)
+	case e.Origin != "" && e.Origin != OriginDefault:
+		return fmt.Sprintf("%s: %s: %v", e.Origin, e.Key, e.Err)
+	default:
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+}
+
+func (e *SettingError) Unwrap() error {
+	return e.Err
+}
+
+// decodeErrorPattern matches the errors of the TOML decoder about a value of the wrong type.
+var decodeErrorPattern = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+
+// decodeError turns an error decoding the config file at path into a *SettingError when
+// it is about a setting.
+func decodeError(path string, err error) error {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) && parseErr.LastKey != "" {
+		return &SettingError{Key: parseErr.LastKey, Origin: path, Line: parseErr.Position.Line, Err: errors.New(parseErr.Message)}
+	}
+	if m := decodeErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &SettingError{Key: m[2], Origin: path, Line: line, Err: errors.New(m[3])}
+	}
+	return fmt.Errorf("error loading %s: %w", path, err)
+}
+
+// validate checks the settings that decoding alone cannot.
+func validate(cfg Config) error {
+	for key, value := range map[string]int{
+		"pomodoro.pomodoro_duration":    cfg.Pomodoro.PomodoroDuration,
+		"pomodoro.short_break_duration": cfg.Pomodoro.ShortBreakDuration,
+		"pomodoro.long_break_duration":  cfg.Pomodoro.LongBreakDuration,
+		"pomodoro.long_break_interval":  cfg.Pomodoro.LongBreakInterval,
+	} {
+		if value < 0 {
+			return &SettingError{Key: key, Err: errors.New("must not be negative")}
+		}
+	}
+	switch cfg.General.ProjectNaming {
+	case "", ProjectNamingRepo, ProjectNamingOwnerRepo:
+	default:
+		return &SettingError{Key: "general.project_naming", Err: fmt.Errorf("unknown scheme '%s' (expected '%s' or '%s')", cfg.General.ProjectNaming, ProjectNamingRepo, ProjectNamingOwnerRepo)}
+	}
+	if err := cfg.Branch.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Filename.Validate(); err != nil {
+		return err
+	}
+	if cfg.Storage.Layout != "" {
+		if err := ValidateLayout(cfg.Storage.Layout); err != nil {
+			return &SettingError{Key: "storage.layout", Err: err}
+		}
+	}
+	return cfg.NoteFormat.Validate()
+}
+
+// locate completes a SettingError with the origin of its setting and, for a config file,
+// the line setting it.
+func locate(err error, origins Origins) error {
+	var settingErr *SettingError
+	if !errors.As(err, &settingErr) || settingErr.Origin != "" {
+		return err
+	}
+	settingErr.Origin = origins[settingErr.Key]
+	if filepath.IsAbs(settingErr.Origin) {
+		settingErr.Line = keyLine(settingErr.Origin, settingErr.Key)
+	}
+	return err
+}
+
+// keyLine returns the line of the config file at path setting key, or 0 when it cannot
+// be found. It understands table headers and dotted keys, which is what config files
+// written by hand use.
+func keyLine(path, key string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	key = normalizeKey(key)
+	table := ""
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if end := strings.Index(line, "]"); end > 0 {
+				table = normalizeKey(strings.Trim(line[:end], "[]"))
+				if table == key {
+					return i + 1
+				}
+			}
+			continue
+		}
+		name, _, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		full := normalizeKey(name)
+		if table != "" {
+			full = table + "." + full
+		}
+		if full == key {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// normalizeKey removes the spaces and quotes around the parts of a TOML key.
+func normalizeKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
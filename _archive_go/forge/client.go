@@ -0,0 +1,74 @@
+package forge
+
+import (
+	"fmt"
+	"io"
+
+	"tasky/utils"
+)
+
+// client runs the command line client of a forge. In dry-run mode, the commands changing
+// something are printed instead of run.
+type client struct {
+	dryRun bool
+	out    io.Writer
+}
+
+// query runs a read-only command, even in dry-run mode, and returns its output.
+func (c client) query(name string, args ...string) (string, error) {
+	return utils.RunCmd(name, args...)
+}
+
+// exec runs a command changing something and returns its output; in dry-run mode it only
+// prints the command and returns "".
+func (c client) exec(name string, args ...string) (string, error) {
+	if c.dryRun {
+		fmt.Fprintln(c.out, "$", utils.ShellQuote(append([]string{name}, args...)))
+		return "", nil
+	}
+	return utils.RunCmd(name, args...)
+}
+
+// branchExists reports whether the local branch exists.
+func (c client) branchExists(branch string) bool {
+	_, err := c.query("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// checkout checks out branch, creating it from the current commit if needed.
+func (c client) checkout(branch string) error {
+	args := []string{"checkout", branch}
+	if !c.branchExists(branch) {
+		args = []string{"checkout", "-b", branch}
+	}
+	_, err := c.exec("git", args...)
+	return err
+}
+
+// created returns the number and URL of the issue or pull request created by a command
+// that printed output. Nothing is created in dry-run mode.
+func (c client) created(output string) (int, string, error) {
+	if c.dryRun {
+		return 0, "", nil
+	}
+	return parseNumber(output)
+}
+
+// DryRun returns f printing to w the commands that would change something instead of
+// running them; read-only commands, such as viewing an issue, still run. Other forges,
+// such as Fake, are returned unchanged.
+func DryRun(f Forge, w io.Writer) Forge {
+	c := client{dryRun: true, out: w}
+	switch f := f.(type) {
+	case GitHub:
+		f.client = c
+		return f
+	case GitLab:
+		f.client = c
+		return f
+	case Gitea:
+		f.client = c
+		return f
+	}
+	return f
+}
@@ -0,0 +1,76 @@
+package forge
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"tasky/utils"
+)
+
+func TestDevelopIssue(t *testing.T) {
+	exists := utils.Expect{Command: "git rev-parse --verify --quiet refs/heads/12-fix", Output: "abc123"}
+	missing := utils.Expect{Command: "git rev-parse --verify --quiet refs/heads/12-fix", Err: errors.New("exit status 1")}
+	issue := Issue{Number: 12, Title: "Fix"}
+	tests := []struct {
+		name   string
+		forge  Forge
+		script []utils.Expect
+		dryRun string // commands printed in dry-run mode; empty to run them
+	}{
+		{
+			name:   "GitHub, new branch",
+			forge:  GitHub{},
+			script: []utils.Expect{missing, {Command: "gh issue develop 12 --checkout --name 12-fix"}},
+		},
+		{
+			name:   "GitHub, existing branch",
+			forge:  GitHub{},
+			script: []utils.Expect{exists, {Command: "git checkout 12-fix"}},
+		},
+		{
+			name:   "GitLab",
+			forge:  GitLab{},
+			script: []utils.Expect{missing, {Command: "git checkout -b 12-fix"}},
+		},
+		{
+			name:   "Gitea",
+			forge:  Gitea{},
+			script: []utils.Expect{exists, {Command: "git checkout 12-fix"}},
+		},
+		{
+			name:   "Gitea, dry run",
+			forge:  Gitea{},
+			script: []utils.Expect{missing},
+			dryRun: "$ git checkout -b 12-fix\n",
+		},
+		{
+			name:   "GitHub, dry run",
+			forge:  GitHub{},
+			script: []utils.Expect{exists},
+			dryRun: "$ git checkout 12-fix\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay := utils.NewReplay(tt.script...)
+			utils.SetRunner(replay)
+			t.Cleanup(func() { utils.SetRunner(nil) })
+			var out bytes.Buffer
+			f := tt.forge
+			if tt.dryRun != "" {
+				f = DryRun(f, &out)
+			}
+
+			if err := f.DevelopIssue(issue, "12-fix"); err != nil {
+				t.Fatalf("DevelopIssue: %v", err)
+			}
+			if err := replay.Verify(); err != nil {
+				t.Errorf("commands: %v\nran: %q", err, replay.Calls)
+			}
+			if out.String() != tt.dryRun {
+				t.Errorf("printed %q, want %q", out.String(), tt.dryRun)
+			}
+		})
+	}
+}
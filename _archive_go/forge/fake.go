@@ -0,0 +1,122 @@
+package forge
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Fake is a Forge keeping issues and pull requests in memory, for tests and dry runs;
+// install it with SetForge. Calls records every call, such as "CloseIssue 3".
+type Fake struct {
+	Issues       map[int]*Issue
+	PullRequests map[int]*PullRequest
+	// Merged holds the numbers of the merged pull requests.
+	Merged map[int]bool
+	// Branch is the branch checked out by DevelopIssue, from which pull requests are
+	// created.
+	Branch string
+	Calls  []string
+	// Err, when set, is returned by every call.
+	Err error
+
+	next int
+}
+
+// NewFake returns an empty Fake.
+func NewFake() *Fake {
+	return &Fake{Issues: make(map[int]*Issue), PullRequests: make(map[int]*PullRequest), Merged: make(map[int]bool)}
+}
+
+func (f *Fake) Name() string { return "Fake" }
+
+func (f *Fake) call(format string, args ...any) error {
+	f.Calls = append(f.Calls, fmt.Sprintf(format, args...))
+	return f.Err
+}
+
+// number returns the next issue or pull request number; they share a sequence as on GitHub.
+func (f *Fake) number() int {
+	f.next++
+	return f.next
+}
+
+func (f *Fake) CreateIssue(title, body string) (Issue, error) {
+	if err := f.call("CreateIssue %s", title); err != nil {
+		return Issue{}, err
+	}
+	issue := Issue{Number: f.number(), Title: title, State: StateOpen}
+	issue.URL = "https://forge.example/issues/" + strconv.Itoa(issue.Number)
+	f.Issues[issue.Number] = &issue
+	return issue, nil
+}
+
+func (f *Fake) ViewIssue(number int) (Issue, error) {
+	if err := f.call("ViewIssue %d", number); err != nil {
+		return Issue{}, err
+	}
+	issue, ok := f.Issues[number]
+	if !ok {
+		return Issue{}, fmt.Errorf("issue #%d not found", number)
+	}
+	return *issue, nil
+}
+
+func (f *Fake) CloseIssue(number int) error {
+	if err := f.call("CloseIssue %d", number); err != nil {
+		return err
+	}
+	issue, ok := f.Issues[number]
+	if !ok {
+		return fmt.Errorf("issue #%d not found", number)
+	}
+	issue.State = StateClosed
+	return nil
+}
+
+func (f *Fake) DevelopIssue(issue Issue, branch string) error {
+	if err := f.call("DevelopIssue %d %s", issue.Number, branch); err != nil {
+		return err
+	}
+	f.Branch = branch
+	return nil
+}
+
+func (f *Fake) CreatePullRequest(title, body string, draft bool) (PullRequest, error) {
+	if err := f.call("CreatePullRequest %s draft=%t", title, draft); err != nil {
+		return PullRequest{}, err
+	}
+	pr := PullRequest{Number: f.number(), Branch: f.Branch, State: StateOpen}
+	pr.URL = "https://forge.example/pulls/" + strconv.Itoa(pr.Number)
+	f.PullRequests[pr.Number] = &pr
+	return pr, nil
+}
+
+func (f *Fake) MergePullRequest(pr PullRequest, strategy string, deleteBranch bool) error {
+	if err := f.call("MergePullRequest %d %s deleteBranch=%t", pr.Number, strategy, deleteBranch); err != nil {
+		return err
+	}
+	stored, ok := f.PullRequests[pr.Number]
+	if !ok {
+		return fmt.Errorf("pull request #%d not found", pr.Number)
+	}
+	stored.State = StateMerged
+	f.Merged[pr.Number] = true
+	return nil
+}
+
+func (f *Fake) FindPullRequest(branch string) (*PullRequest, error) {
+	if err := f.call("FindPullRequest %s", branch); err != nil {
+		return nil, err
+	}
+	var found *PullRequest
+	for _, pr := range f.PullRequests {
+		if pr.Branch == branch && (found == nil || pr.Number > found.Number) {
+			found = pr
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	pr := *found
+	return &pr, nil
+}
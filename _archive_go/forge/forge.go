@@ -0,0 +1,156 @@
+// Package forge talks to the platform hosting a Git repository, such as GitHub, through
+// its command line client.
+package forge
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// Kinds of forges, as set in forge.kind and forge.hosts.
+const (
+	KindGitHub  = "github"
+	KindGitLab  = "gitlab"
+	KindGitea   = "gitea"
+	KindForgejo = "forgejo"
+)
+
+// Merge strategies of MergePullRequest.
+const (
+	MergeSquash = "squash"
+	MergeCommit = "merge"
+	MergeRebase = "rebase"
+)
+
+// Issue is an issue of a forge.
+type Issue struct {
+	Number int
+	Title  string
+	URL    string
+	// State is "open" or "closed".
+	State string
+}
+
+// PullRequest is a pull request, or a merge request on GitLab.
+type PullRequest struct {
+	Number int
+	URL    string
+	// Branch is the branch merged by the pull request, if known.
+	Branch string
+	// State is "open", "merged" or "closed".
+	State string
+}
+
+// States of issues and pull requests.
+const (
+	StateOpen   = "open"
+	StateMerged = "merged"
+	StateClosed = "closed"
+)
+
+// Forge is a platform hosting the repository of the current directory. Pull requests are
+// opened from, and merged into the default branch of, the current branch.
+type Forge interface {
+	// Name is the name of the platform, such as "GitHub", for messages.
+	Name() string
+	CreateIssue(title, body string) (Issue, error)
+	ViewIssue(number int) (Issue, error)
+	CloseIssue(number int) error
+	// DevelopIssue checks out branch for working on an issue, creating it if needed and
+	// linking it to the issue when the forge can.
+	DevelopIssue(issue Issue, branch string) error
+	CreatePullRequest(title, body string, draft bool) (PullRequest, error)
+	// FindPullRequest returns the latest pull request of branch, whatever its state, or
+	// nil if there is none.
+	FindPullRequest(branch string) (*PullRequest, error)
+	// MergePullRequest merges a pull request with strategy, one of MergeSquash,
+	// MergeCommit and MergeRebase, then deletes its branch if deleteBranch is set. A zero
+	// pr is the pull request of the current branch.
+	MergePullRequest(pr PullRequest, strategy string, deleteBranch bool) error
+}
+
+// override replaces the detected forge when set, see SetForge.
+var override Forge
+
+// SetForge makes every subsequent New call return f instead of the forge of the
+// repository. Passing nil restores the detection. It is meant for tests and for programs
+// embedding tasky.
+func SetForge(f Forge) {
+	override = f
+}
+
+// New returns the forge hosting the repository of the current directory: the one set in
+// forge.kind, or else the one its remote points to, see utils.RepositoryRemote.
+func New(cfg config.Config) (Forge, error) {
+	if override != nil {
+		return override, nil
+	}
+	kind := cfg.Forge.Kind
+	if kind == "" {
+		if !utils.IsGitRepository() {
+			return nil, fmt.Errorf("not in a Git repository")
+		}
+		remote, err := utils.RepositoryRemote()
+		if err != nil {
+			return nil, err
+		}
+		if kind = detectKind(cfg, remote.Host); kind == "" {
+			return nil, fmt.Errorf("cannot tell which forge hosts %s: set forge.kind, or the host in [forge.hosts]", remote.Host)
+		}
+	}
+	switch kind {
+	case KindGitHub:
+		return GitHub{}, nil
+	case KindGitLab:
+		return GitLab{}, nil
+	case KindGitea, KindForgejo:
+		return Gitea{forgejo: kind == KindForgejo}, nil
+	default:
+		return nil, fmt.Errorf("unknown forge '%s' (expected %s, %s, %s or %s)", kind, KindGitHub, KindGitLab, KindGitea, KindForgejo)
+	}
+}
+
+// detectKind returns the kind of the forge at host, from forge.hosts or from the names of
+// the public instances, or "" if it is unknown.
+func detectKind(cfg config.Config, host string) string {
+	if kind, ok := cfg.Forge.Hosts[host]; ok {
+		return kind
+	}
+	switch {
+	case strings.Contains(host, "github"):
+		return KindGitHub
+	case strings.Contains(host, "gitlab"):
+		return KindGitLab
+	case host == "codeberg.org" || strings.Contains(host, "forgejo"):
+		return KindForgejo
+	case strings.Contains(host, "gitea"):
+		return KindGitea
+	}
+	return ""
+}
+
+// numberPattern finds the number in the URL of an issue or pull request printed by the
+// clients.
+var numberPattern = regexp.MustCompile(`/(?:issues|pull|pulls|merge_requests)/([0-9]+)`)
+
+// parseNumber returns the number of the last issue or pull request URL in output.
+func parseNumber(output string) (int, string, error) {
+	matches := numberPattern.FindAllStringSubmatchIndex(output, -1)
+	if len(matches) == 0 {
+		return 0, "", fmt.Errorf("no issue or pull request URL in %q", output)
+	}
+	m := matches[len(matches)-1]
+	number, _ := strconv.Atoi(output[m[2]:m[3]])
+	// The URL is the whitespace-separated word around the match.
+	start := strings.LastIndexAny(output[:m[0]], " \t\n") + 1
+	end := m[1]
+	for end < len(output) && !strings.ContainsRune(" \t\r\n", rune(output[end])) {
+		end++
+	}
+	return number, output[start:end], nil
+}
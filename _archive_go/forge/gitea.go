@@ -0,0 +1,137 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tasky/utils"
+)
+
+// Gitea is a Gitea or Forgejo server, such as codeberg.org, used through tea.
+type Gitea struct {
+	client
+	forgejo bool
+}
+
+func (g Gitea) Name() string {
+	if g.forgejo {
+		return "Forgejo"
+	}
+	return "Gitea"
+}
+
+func (g Gitea) CreateIssue(title, body string) (Issue, error) {
+	output, err := g.exec("tea", "issues", "create", "--title", title, "--description", body)
+	if err != nil {
+		return Issue{}, err
+	}
+	number, url, err := g.created(output)
+	if err != nil {
+		return Issue{}, err
+	}
+	return Issue{Number: number, Title: title, URL: url, State: StateOpen}, nil
+}
+
+func (g Gitea) ViewIssue(number int) (Issue, error) {
+	output, err := g.query("tea", "issues", strconv.Itoa(number), "--output", "json")
+	if err != nil {
+		return Issue{}, err
+	}
+	var result struct {
+		Index int    `json:"index"`
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return Issue{}, fmt.Errorf("failed to parse issue JSON: %w", err)
+	}
+	if result.Index == 0 {
+		result.Index = number
+	}
+	return Issue{Number: result.Index, Title: result.Title, URL: result.URL, State: strings.ToLower(result.State)}, nil
+}
+
+func (g Gitea) CloseIssue(number int) error {
+	_, err := g.exec("tea", "issues", "close", strconv.Itoa(number))
+	return err
+}
+
+func (g Gitea) DevelopIssue(issue Issue, branch string) error {
+	return g.checkout(branch)
+}
+
+// CreatePullRequest opens a pull request; Gitea marks drafts by a "WIP:" title prefix.
+func (g Gitea) CreatePullRequest(title, body string, draft bool) (PullRequest, error) {
+	if draft {
+		title = "WIP: " + title
+	}
+	output, err := g.exec("tea", "pulls", "create", "--title", title, "--description", body)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	number, url, err := g.created(output)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	return PullRequest{Number: number, URL: url, State: StateOpen}, nil
+}
+
+func (g Gitea) FindPullRequest(branch string) (*PullRequest, error) {
+	output, err := g.query("tea", "pulls", "list", "--state", "all", "--fields", "index,state,head,url", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		Index string `json:"index"`
+		State string `json:"state"`
+		Head  string `json:"head"`
+		URL   string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse pull request JSON: %w", err)
+	}
+	var found *PullRequest
+	for _, r := range results {
+		number, _ := strconv.Atoi(r.Index)
+		if r.Head != branch || found != nil && found.Number > number {
+			continue
+		}
+		found = &PullRequest{Number: number, URL: r.URL, Branch: branch, State: strings.ToLower(r.State)}
+	}
+	return found, nil
+}
+
+// MergePullRequest merges pr, found from the current branch when it has no number; tea
+// cannot delete its branch, so it is deleted with git.
+func (g Gitea) MergePullRequest(pr PullRequest, strategy string, deleteBranch bool) error {
+	branch := pr.Branch
+	if branch == "" {
+		current, err := utils.GetCurrentBranchName()
+		if err != nil {
+			return err
+		}
+		branch = current
+	}
+	if pr.Number == 0 {
+		// tea needs the index of the pull request.
+		found, err := g.FindPullRequest(branch)
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return fmt.Errorf("no pull request found for branch %s", branch)
+		}
+		pr.Number = found.Number
+	}
+	if _, err := g.exec("tea", "pulls", "merge", "--style", strategy, strconv.Itoa(pr.Number)); err != nil {
+		return err
+	}
+	if !deleteBranch {
+		return nil
+	}
+	_, err := g.exec("git", "push", "origin", "--delete", branch)
+	return err
+}
@@ -0,0 +1,53 @@
+package forge
+
+import (
+	"strings"
+	"testing"
+
+	"tasky/utils"
+)
+
+func TestGiteaMergeFindsPullRequestOfBranch(t *testing.T) {
+	list := "tea pulls list --state all --fields index,state,head,url --output json"
+	tests := []struct {
+		name    string
+		script  []utils.Expect
+		wantErr string
+	}{
+		{
+			name: "found",
+			script: []utils.Expect{
+				{Command: "git rev-parse --abbrev-ref HEAD", Output: "12-fix"},
+				{Command: list, Output: `[{"index":"3","state":"open","head":"other","url":"u3"},{"index":"7","state":"open","head":"12-fix","url":"u7"}]`},
+				{Command: "tea pulls merge --style squash 7"},
+				{Command: "git push origin --delete 12-fix"},
+			},
+		},
+		{
+			name: "none",
+			script: []utils.Expect{
+				{Command: "git rev-parse --abbrev-ref HEAD", Output: "12-fix"},
+				{Command: list, Output: `[{"index":"3","state":"open","head":"other","url":"u3"}]`},
+			},
+			wantErr: "no pull request found for branch 12-fix",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay := utils.NewReplay(tt.script...)
+			utils.SetRunner(replay)
+			t.Cleanup(func() { utils.SetRunner(nil) })
+
+			err := Gitea{}.MergePullRequest(PullRequest{}, MergeSquash, true)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("MergePullRequest: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("MergePullRequest: got %v, want %q", err, tt.wantErr)
+			}
+			if err := replay.Verify(); err != nil {
+				t.Errorf("commands: %v\nran: %q", err, replay.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GitHub is github.com or a GitHub Enterprise server, used through gh.
+type GitHub struct {
+	client
+}
+
+func (GitHub) Name() string { return "GitHub" }
+
+func (g GitHub) CreateIssue(title, body string) (Issue, error) {
+	output, err := g.exec("gh", "issue", "create", "--title", title, "--body", body)
+	if err != nil {
+		return Issue{}, err
+	}
+	number, url, err := g.created(output)
+	if err != nil {
+		return Issue{}, err
+	}
+	return Issue{Number: number, Title: title, URL: url, State: StateOpen}, nil
+}
+
+func (g GitHub) ViewIssue(number int) (Issue, error) {
+	output, err := g.query("gh", "issue", "view", strconv.Itoa(number), "--json", "number,title,url,state")
+	if err != nil {
+		return Issue{}, err
+	}
+	var issue Issue
+	if err := json.Unmarshal([]byte(output), &issue); err != nil {
+		return Issue{}, fmt.Errorf("failed to parse issue JSON: %w", err)
+	}
+	issue.State = strings.ToLower(issue.State)
+	return issue, nil
+}
+
+func (g GitHub) CloseIssue(number int) error {
+	_, err := g.exec("gh", "issue", "close", strconv.Itoa(number))
+	return err
+}
+
+// DevelopIssue links a new branch to the issue; an existing branch is only checked out.
+func (g GitHub) DevelopIssue(issue Issue, branch string) error {
+	if g.branchExists(branch) {
+		_, err := g.exec("git", "checkout", branch)
+		return err
+	}
+	_, err := g.exec("gh", "issue", "develop", strconv.Itoa(issue.Number), "--checkout", "--name", branch)
+	return err
+}
+
+func (g GitHub) CreatePullRequest(title, body string, draft bool) (PullRequest, error) {
+	args := []string{"pr", "create", "--title", title, "--body", body}
+	if draft {
+		args = append(args, "--draft")
+	}
+	output, err := g.exec("gh", args...)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	number, url, err := g.created(output)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	return PullRequest{Number: number, URL: url, State: StateOpen}, nil
+}
+
+func (g GitHub) FindPullRequest(branch string) (*PullRequest, error) {
+	output, err := g.query("gh", "pr", "list", "--head", branch, "--state", "all", "--limit", "1", "--json", "number,url,state")
+	if err != nil {
+		return nil, err
+	}
+	var prs []PullRequest
+	if err := json.Unmarshal([]byte(output), &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse pull request JSON: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	pr := prs[0]
+	pr.Branch, pr.State = branch, strings.ToLower(pr.State)
+	return &pr, nil
+}
+
+func (g GitHub) MergePullRequest(pr PullRequest, strategy string, deleteBranch bool) error {
+	args := []string{"pr", "merge"}
+	if pr.Number != 0 {
+		args = append(args, strconv.Itoa(pr.Number))
+	}
+	args = append(args, "--"+strategy)
+	if deleteBranch {
+		args = append(args, "--delete-branch")
+	}
+	_, err := g.exec("gh", args...)
+	return err
+}
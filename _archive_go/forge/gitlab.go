@@ -0,0 +1,116 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// GitLab is gitlab.com or a self-hosted GitLab, used through glab. Its pull requests are
+// merge requests.
+type GitLab struct {
+	client
+}
+
+func (GitLab) Name() string { return "GitLab" }
+
+func (g GitLab) CreateIssue(title, body string) (Issue, error) {
+	output, err := g.exec("glab", "issue", "create", "--title", title, "--description", body, "--yes")
+	if err != nil {
+		return Issue{}, err
+	}
+	number, url, err := g.created(output)
+	if err != nil {
+		return Issue{}, err
+	}
+	return Issue{Number: number, Title: title, URL: url, State: StateOpen}, nil
+}
+
+func (g GitLab) ViewIssue(number int) (Issue, error) {
+	output, err := g.query("glab", "issue", "view", strconv.Itoa(number), "--output", "json")
+	if err != nil {
+		return Issue{}, err
+	}
+	var result struct {
+		IID    int    `json:"iid"`
+		Title  string `json:"title"`
+		WebURL string `json:"web_url"`
+		State  string `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return Issue{}, fmt.Errorf("failed to parse issue JSON: %w", err)
+	}
+	state := StateOpen
+	if result.State == "closed" {
+		state = StateClosed
+	}
+	return Issue{Number: result.IID, Title: result.Title, URL: result.WebURL, State: state}, nil
+}
+
+func (g GitLab) CloseIssue(number int) error {
+	_, err := g.exec("glab", "issue", "close", strconv.Itoa(number))
+	return err
+}
+
+func (g GitLab) DevelopIssue(issue Issue, branch string) error {
+	return g.checkout(branch)
+}
+
+func (g GitLab) CreatePullRequest(title, body string, draft bool) (PullRequest, error) {
+	args := []string{"mr", "create", "--title", title, "--description", body, "--yes"}
+	if draft {
+		args = append(args, "--draft")
+	}
+	output, err := g.exec("glab", args...)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	number, url, err := g.created(output)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	return PullRequest{Number: number, URL: url, State: StateOpen}, nil
+}
+
+func (g GitLab) FindPullRequest(branch string) (*PullRequest, error) {
+	output, err := g.query("glab", "mr", "list", "--source-branch", branch, "--all", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		IID    int    `json:"iid"`
+		WebURL string `json:"web_url"`
+		State  string `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse merge request JSON: %w", err)
+	}
+	var found *PullRequest
+	for _, r := range results {
+		if found != nil && found.Number > r.IID {
+			continue
+		}
+		state := r.State
+		if state == "opened" {
+			state = StateOpen
+		}
+		found = &PullRequest{Number: r.IID, URL: r.WebURL, Branch: branch, State: state}
+	}
+	return found, nil
+}
+
+// MergePullRequest merges pr; a merge commit is GitLab's default, so it has no flag.
+func (g GitLab) MergePullRequest(pr PullRequest, strategy string, deleteBranch bool) error {
+	args := []string{"mr", "merge"}
+	if pr.Number != 0 {
+		args = append(args, strconv.Itoa(pr.Number))
+	}
+	if strategy != MergeCommit {
+		args = append(args, "--"+strategy)
+	}
+	if deleteBranch {
+		args = append(args, "--remove-source-branch")
+	}
+	_, err := g.exec("glab", append(args, "--yes")...)
+	return err
+}
@@ -0,0 +1,215 @@
+// Package output renders lists of tasks for the terminal or for scripts. Every command
+// that prints tasks goes through Write so they all accept the same --format values.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"tasky/config"
+	"tasky/task"
+)
+
+// Built-in formats. Any other value containing "{{" is used as a text/template
+// executed once per task.
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+)
+
+// FormatUsage describes the accepted formats, for flag help texts.
+const FormatUsage = "Output format: text, json, ndjson, csv, tsv or a Go template such as '{{.ID}} {{.Title}}'"
+
+// Record is the machine-readable view of a task: every frontmatter field plus where the
+// note is stored.
+type Record struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Status        string   `json:"status"`
+	CreatedDate   string   `json:"created_date"`
+	StartDate     string   `json:"start_date"`
+	DoneDate      string   `json:"done_date"`
+	Issue         int      `json:"issue"`
+	Branch        string   `json:"branch"`
+	PomodoroCount int      `json:"pomodoro_count"`
+	Duration      int      `json:"duration"`
+	Tags          []string `json:"tags"`
+	Project       string   `json:"project"`
+	Path          string   `json:"path"`
+	Description   string   `json:"description,omitempty"`
+}
+
+// columns are the header of the csv and tsv formats.
+var columns = []string{"id", "title", "status", "created_date", "start_date", "done_date", "issue", "branch", "pomodoro_count", "duration", "tags", "project", "path"}
+
+// NewRecord converts an entry. Path is the note's file when known, its store name otherwise.
+func NewRecord(e task.Entry) Record {
+	path := e.File
+	if path == "" {
+		path = e.Path
+	}
+	tags := []string(e.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return Record{
+		ID:            e.ID,
+		Title:         e.Title,
+		Status:        e.Status,
+		CreatedDate:   e.CreatedDate,
+		StartDate:     e.StartDate,
+		DoneDate:      e.DoneDate,
+		Issue:         e.Issue,
+		Branch:        e.Branch,
+		PomodoroCount: e.PomodoroCount,
+		Duration:      e.Duration,
+		Tags:          tags,
+		Project:       e.Project,
+		Path:          path,
+		Description:   e.Description,
+	}
+}
+
+func (r Record) fields() []string {
+	return []string{
+		r.ID, r.Title, r.Status, r.CreatedDate, r.StartDate, r.DoneDate,
+		strconv.Itoa(r.Issue), r.Branch, strconv.Itoa(r.PomodoroCount), strconv.Itoa(r.Duration),
+		strings.Join(r.Tags, ","), r.Project, r.Path,
+	}
+}
+
+// Validate reports whether format is usable, so commands can fail before doing any work.
+func Validate(format string) error {
+	switch format {
+	case "", FormatText, FormatJSON, FormatNDJSON, FormatCSV, FormatTSV:
+		return nil
+	}
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+	_, err := template.New("format").Parse(format)
+	return err
+}
+
+// NeedsDescription reports whether format prints the tasks' descriptions, which callers
+// then have to load.
+func NeedsDescription(format string) bool {
+	return strings.Contains(format, ".Description")
+}
+
+// Write prints entries to w in the given format. An empty format means text.
+func Write(w io.Writer, format string, entries []task.Entry) error {
+	switch format {
+	case "", FormatText:
+		return writeText(w, entries)
+	case FormatJSON:
+		records := make([]Record, 0, len(entries))
+		for _, e := range entries {
+			records = append(records, NewRecord(e))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(NewRecord(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		return writeCSV(w, entries)
+	case FormatTSV:
+		return writeTSV(w, entries)
+	default:
+		return writeTemplate(w, format, entries)
+	}
+}
+
+// StatusSymbol returns the symbol shown in front of a task in text output.
+func StatusSymbol(status string) string {
+	switch status {
+	case config.StatusTodo:
+		return config.SymbolTodo
+	case config.StatusInProgress:
+		return config.SymbolDoing
+	case config.StatusDone:
+		return config.SymbolDone
+	default:
+		return "?"
+	}
+}
+
+func writeText(w io.Writer, entries []task.Entry) error {
+	for _, t := range entries {
+		var err error
+		if t.Issue != 0 {
+			_, err = fmt.Fprintf(w, "%s %s #%d %s\n", StatusSymbol(t.Status), t.ID, t.Issue, t.Title)
+		} else {
+			_, err = fmt.Fprintf(w, "%s %s %s\n", StatusSymbol(t.Status), t.ID, t.Title)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, entries []task.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write(NewRecord(e).fields()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTSV writes one line per task with tab separated fields. TSV has no quoting, so
+// tabs and line breaks inside fields are replaced with spaces and everything else is
+// written as is, for cut and awk.
+func writeTSV(w io.Writer, entries []task.Entry) error {
+	sanitize := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	if _, err := fmt.Fprintln(w, strings.Join(columns, "\t")); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fields := NewRecord(e).fields()
+		for i, f := range fields {
+			fields[i] = sanitize.Replace(f)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTemplate(w io.Writer, format string, entries []task.Entry) error {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := tmpl.Execute(w, NewRecord(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"tasky/config"
+	"tasky/task"
+)
+
+var quoted = []task.Entry{{
+	Task: config.Task{Frontmatter: config.Frontmatter{
+		ID: "abc123", Title: "Say \"hi\",\tthen\nleave", Status: config.StatusTodo, Issue: 7, Tags: config.Tags{"a", "b"},
+	}},
+	Project: "proj",
+	Path:    "say-hi.md",
+}}
+
+func TestWriteTSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatTSV, quoted); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(columns, "\t") + "\n" +
+		"abc123\tSay \"hi\", then leave\ttodo\t\t\t\t7\t\t0\t0\ta,b\tproj\tsay-hi.md\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, quoted); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(columns, ",") + "\n" +
+		"abc123,\"Say \"\"hi\"\",\tthen\nleave\",todo,,,,7,,0,0,\"a,b\",proj,say-hi.md\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package pomodoro
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"tasky/utils"
+)
+
+// phaseEnd tells how a phase ended.
+type phaseEnd int
+
+const (
+	phaseExpired phaseEnd = iota // ran for its whole duration
+	phaseSkipped                 // cut short to move on to the next phase
+	phaseAborted                 // cut short to end the cycle
+)
+
+const controlsHelp = "p: pause/resume  s: skip  a: abort  +/-: one minute more/less"
+
+// runPhase shows the countdown of the machine's current phase and reacts to keypresses
+// until the phase expires, is skipped or aborted. SIGINT and SIGTERM abort the phase so
+// the caller can still record it.
+func runPhase(m *Machine) phaseEnd {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	keys := false
+	if utils.IsTerminal() {
+		if restore, err := utils.MakeRaw(); err == nil {
+			defer restore()
+			keys = true
+			fmt.Print(controlsHelp + "\r\n")
+		}
+	}
+
+	end := phaseExpired
+loop:
+	for !m.Expired() {
+		drawPhase(m)
+		select {
+		case <-signals:
+			end = phaseAborted
+			break loop
+		default:
+		}
+		if !keys {
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+		// ReadKey waits at most a tenth of a second for a key.
+		switch utils.ReadKey() {
+		case "p", " ":
+			m.TogglePause()
+		case "s":
+			end = phaseSkipped
+			break loop
+		case "a", utils.KeyCtrlC:
+			end = phaseAborted
+			break loop
+		case "+", "=":
+			m.Extend(time.Minute)
+		case "-":
+			m.Extend(-time.Minute)
+		}
+	}
+	drawPhase(m)
+	fmt.Print("\r\n")
+	return end
+}
@@ -0,0 +1,326 @@
+package pomodoro
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"tasky/config"
+	"tasky/task"
+)
+
+// Commands understood by the daemon.
+const (
+	CommandStatus = "status"
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandStop   = "stop"
+)
+
+// ErrNotRunning is returned by Send when no daemon is listening.
+var ErrNotRunning = errors.New("no Pomodoro is running in the background")
+
+// Status is the state of the daemon's cycle, as returned for every command.
+type Status struct {
+	// Phase is the running phase, or idle while waiting to be resumed into Next.
+	Phase             Phase         `json:"phase"`
+	Next              Phase         `json:"next"`
+	Paused            bool          `json:"paused"`
+	Remaining         time.Duration `json:"remaining_ns"`
+	Duration          time.Duration `json:"duration_ns"`
+	CompletedInCycle  int           `json:"completed_in_cycle"`
+	LongBreakInterval int           `json:"long_break_interval"`
+	Project           string        `json:"project,omitempty"`
+	TaskID            string        `json:"task_id,omitempty"`
+	TaskTitle         string        `json:"task_title,omitempty"`
+	// Stopped is set in the reply to a stop command.
+	Stopped bool   `json:"stopped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Short renders the status on a single line, for shell prompts and status bars.
+func (s Status) Short() string {
+	icon := "🍅"
+	if s.Phase == PhaseShortBreak || s.Phase == PhaseLongBreak {
+		icon = "☕"
+	}
+	if s.Phase == PhaseIdle {
+		return fmt.Sprintf("⏸ next: %s", s.Next.Label())
+	}
+	line := fmt.Sprintf("%s %s", icon, FormatRemaining(s.Remaining))
+	if s.Paused {
+		line = "⏸ " + line
+	}
+	return line
+}
+
+// FormatRemaining formats a countdown as mm:ss, rounding up so that it reads 00:00 only
+// once the time is over.
+func FormatRemaining(d time.Duration) string {
+	d = (d + time.Second - 1).Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// SocketPath returns the Unix socket the daemon listens on, pomodoro.sock in the
+// directory of daemonDir. It fails if that directory exists but is not private.
+func SocketPath() (string, error) {
+	dir, err := daemonDir(false)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pomodoro.sock"), nil
+}
+
+// daemonDir returns the directory holding the socket and the log of the daemon: tasky in
+// $XDG_RUNTIME_DIR, or else tasky/run in the user cache directory, created when create
+// is set. An existing directory must be a real directory, owned by the current user and
+// only accessible by them, so that no other user can take over the socket or read the
+// log.
+func daemonDir(create bool) (string, error) {
+	var dir string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dir = filepath.Join(runtimeDir, "tasky")
+	} else {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cacheDir, "tasky", "run")
+	}
+	if create {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+	info, err := os.Lstat(dir)
+	if errors.Is(err, fs.ErrNotExist) && !create {
+		return dir, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	switch {
+	case !info.IsDir():
+		return "", fmt.Errorf("%s is not a directory", dir)
+	case !ok || int(stat.Uid) != os.Getuid():
+		return "", fmt.Errorf("%s is not owned by the current user", dir)
+	case info.Mode().Perm() != 0700:
+		return "", fmt.Errorf("%s is accessible by other users (mode %04o, expected 0700)", dir, info.Mode().Perm())
+	}
+	return dir, nil
+}
+
+type request struct {
+	Command string `json:"command"`
+}
+
+// Send sends a command to the daemon and returns its reply. It returns ErrNotRunning
+// when there is no daemon.
+func Send(command string) (Status, error) {
+	socket, err := SocketPath()
+	if err != nil {
+		return Status{}, err
+	}
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return Status{}, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(request{Command: command}); err != nil {
+		return Status{}, err
+	}
+	var status Status
+	if err := json.NewDecoder(conn).Decode(&status); err != nil {
+		return Status{}, fmt.Errorf("invalid reply from the Pomodoro daemon: %w", err)
+	}
+	if status.Error != "" {
+		return status, errors.New(status.Error)
+	}
+	return status, nil
+}
+
+// Detach starts the daemon in a new background process running "<tasky> pomodoro daemon"
+// from the current directory, and waits until it answers.
+func Detach() (Status, error) {
+	if _, err := Send(CommandStatus); err == nil {
+		return Status{}, errors.New("a Pomodoro is already running in the background")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return Status{}, err
+	}
+	dir, err := daemonDir(true)
+	if err != nil {
+		return Status{}, err
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, "pomodoro.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return Status{}, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, "pomodoro", "daemon")
+	cmd.Env = append(append(os.Environ(), "TASKY_NON_INTERACTIVE=true"), config.OverrideEnv()...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return Status{}, fmt.Errorf("could not start the Pomodoro daemon: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		select {
+		case err := <-exited:
+			return Status{}, fmt.Errorf("the Pomodoro daemon exited (%v), see %s", err, logFile.Name())
+		case <-time.After(50 * time.Millisecond):
+		}
+		if status, err := Send(CommandStatus); err == nil {
+			return status, nil
+		}
+	}
+	return Status{}, fmt.Errorf("the Pomodoro daemon did not start, see %s", logFile.Name())
+}
+
+// daemonCall is a command received by the daemon, answered by its main loop.
+type daemonCall struct {
+	command string
+	reply   chan Status
+	// sent is closed once the reply was written to the client.
+	sent chan struct{}
+}
+
+// RunDaemon runs a Pomodoro cycle without a terminal, controlled through commands sent
+// on the socket. Phases follow each other by themselves when AutoAdvance is set; otherwise
+// the daemon waits for a resume command between phases. Every phase is recorded like in
+// StartPomodoroCycle. It returns after a stop command or SIGINT/SIGTERM.
+func RunDaemon(cfg config.Config) error {
+	dir, err := daemonDir(true)
+	if err != nil {
+		return err
+	}
+	socket := filepath.Join(dir, "pomodoro.sock")
+	if _, err := Send(CommandStatus); err == nil {
+		return errors.New("a Pomodoro is already running in the background")
+	}
+	os.Remove(socket) // left behind by a daemon that did not exit cleanly
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", socket, err)
+	}
+	defer listener.Close()
+
+	active, err := task.FindActiveTask(cfg)
+	if err != nil {
+		fmt.Println("[WARN] Could not find the active task:", err)
+	}
+
+	calls := make(chan daemonCall)
+	go acceptCalls(listener, calls)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	m := NewMachine(SettingsFromConfig(cfg), SystemClock)
+	m.Advance()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			stopDaemon(cfg, active, m)
+			return nil
+		case call := <-calls:
+			status := Status{}
+			switch call.command {
+			case CommandStatus:
+			case CommandPause:
+				m.Pause()
+			case CommandResume:
+				if m.Phase() == PhaseIdle {
+					m.Advance()
+				} else {
+					m.Resume()
+				}
+			case CommandStop:
+				stopDaemon(cfg, active, m)
+				status.Stopped = true
+			default:
+				status.Error = fmt.Sprintf("unknown command '%s'", call.command)
+			}
+			fillStatus(&status, m, active)
+			call.reply <- status
+			if status.Stopped {
+				// Let the client read the reply before the process exits.
+				select {
+				case <-call.sent:
+				case <-time.After(time.Second):
+				}
+				return nil
+			}
+		case <-ticker.C:
+			if m.Expired() {
+				recordSession(cfg, active, m.Complete())
+				if m.Settings().AutoAdvance {
+					m.Advance()
+				}
+			}
+		}
+	}
+}
+
+// stopDaemon records the running phase, if any, as interrupted.
+func stopDaemon(cfg config.Config, active *task.Entry, m *Machine) {
+	if m.Phase() != PhaseIdle {
+		recordSession(cfg, active, m.Interrupt())
+	}
+}
+
+func fillStatus(status *Status, m *Machine, active *task.Entry) {
+	status.Phase = m.Phase()
+	status.Next = m.Next()
+	status.Paused = m.Paused()
+	status.Remaining = m.Remaining()
+	status.Duration = m.Duration()
+	status.CompletedInCycle = m.CompletedInCycle()
+	status.LongBreakInterval = m.Settings().LongBreakInterval
+	if active != nil {
+		status.Project, status.TaskID, status.TaskTitle = active.Project, active.ID, active.Title
+	}
+}
+
+// acceptCalls reads one command per connection and hands it to the daemon's main loop.
+func acceptCalls(listener net.Listener, calls chan<- daemonCall) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			var req request
+			if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+				return
+			}
+			call := daemonCall{command: req.Command, reply: make(chan Status, 1), sent: make(chan struct{})}
+			calls <- call
+			json.NewEncoder(conn).Encode(<-call.reply)
+			close(call.sent)
+		}(conn)
+	}
+}
@@ -0,0 +1,304 @@
+package pomodoro
+
+import (
+	"strings"
+	"time"
+
+	"tasky/config"
+	"tasky/session"
+)
+
+// Phase is the state of a Pomodoro cycle.
+type Phase string
+
+const (
+	PhaseIdle       Phase = "idle"
+	PhaseWork       Phase = "work"
+	PhaseShortBreak Phase = "short_break"
+	PhaseLongBreak  Phase = "long_break"
+)
+
+// SessionKind returns the session log kind recorded for a phase.
+func (p Phase) SessionKind() session.Kind {
+	switch p {
+	case PhaseShortBreak:
+		return session.KindShortBreak
+	case PhaseLongBreak:
+		return session.KindLongBreak
+	default:
+		return session.KindWork
+	}
+}
+
+// Label returns a human readable name for a phase.
+func (p Phase) Label() string {
+	switch p {
+	case PhaseWork:
+		return "Pomodoro"
+	case PhaseShortBreak:
+		return "short break"
+	case PhaseLongBreak:
+		return "long break"
+	default:
+		return "idle"
+	}
+}
+
+// Title returns the label of a phase, capitalized for the start of a sentence.
+func (p Phase) Title() string {
+	label := p.Label()
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// Clock tells the time. Tests can substitute a fake one to drive a Machine.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the Clock backed by time.Now.
+var SystemClock Clock = systemClock{}
+
+// Settings are the durations and rhythm of a cycle.
+type Settings struct {
+	Work              time.Duration
+	ShortBreak        time.Duration
+	LongBreak         time.Duration
+	LongBreakInterval int  // number of work sessions before a long break
+	AutoAdvance       bool // start the next phase without asking
+}
+
+// SettingsFromConfig reads the cycle settings from the config, using the usual defaults
+// (25/5/15 minutes, a long break every 4 Pomodoros) for unset values.
+func SettingsFromConfig(cfg config.Config) Settings {
+	minutes := func(value, fallback int) time.Duration {
+		if value <= 0 {
+			value = fallback
+		}
+		return time.Duration(value) * time.Minute
+	}
+	interval := cfg.Pomodoro.LongBreakInterval
+	if interval <= 0 {
+		interval = 4
+	}
+	return Settings{
+		Work:              minutes(cfg.Pomodoro.PomodoroDuration, 25),
+		ShortBreak:        minutes(cfg.Pomodoro.ShortBreakDuration, 5),
+		LongBreak:         minutes(cfg.Pomodoro.LongBreakDuration, 15),
+		LongBreakInterval: interval,
+		AutoAdvance:       cfg.Pomodoro.AutoAdvance,
+	}
+}
+
+// Outcome describes a finished phase.
+type Outcome struct {
+	Phase Phase
+	Start time.Time
+	End   time.Time
+	// Elapsed is the time the phase actually ran, pauses excluded.
+	Elapsed   time.Duration
+	Completed bool
+}
+
+// Session returns the session log entry of the outcome, attributed to the task taskID of
+// project when they are set.
+func (o Outcome) Session(project, taskID string) session.Session {
+	return session.Session{
+		Start:     o.Start,
+		End:       o.End,
+		Kind:      o.Phase.SessionKind(),
+		Project:   project,
+		TaskID:    taskID,
+		Completed: o.Completed,
+		Elapsed:   o.Elapsed,
+	}
+}
+
+// Machine is the Pomodoro state machine: work sessions alternate with breaks, and every
+// LongBreakInterval-th completed work session is followed by a long break. A phase can be
+// paused, extended or interrupted before its end. The machine only keeps track of time;
+// displaying it is left to the caller.
+type Machine struct {
+	settings Settings
+	clock    Clock
+
+	phase      Phase
+	phaseStart time.Time
+	// extension is the time added to (or removed from) the current phase.
+	extension time.Duration
+	// paused is the time the current phase spent paused, not counting an ongoing pause.
+	paused   time.Duration
+	pausedAt time.Time
+	// last is the phase that ran before the current one.
+	last Phase
+	// completedWork counts the work sessions completed since the last long break.
+	completedWork int
+}
+
+// NewMachine returns an idle Machine. A nil clock means SystemClock.
+func NewMachine(settings Settings, clock Clock) *Machine {
+	if clock == nil {
+		clock = SystemClock
+	}
+	if settings.LongBreakInterval <= 0 {
+		settings.LongBreakInterval = 4
+	}
+	return &Machine{settings: settings, clock: clock, phase: PhaseIdle, last: PhaseIdle}
+}
+
+// Settings returns the settings of the cycle.
+func (m *Machine) Settings() Settings { return m.settings }
+
+// Phase returns the current phase.
+func (m *Machine) Phase() Phase { return m.phase }
+
+// CompletedInCycle returns the number of work sessions completed since the last long break.
+func (m *Machine) CompletedInCycle() int { return m.completedWork }
+
+// Duration returns the length of the current phase, extensions included.
+func (m *Machine) Duration() time.Duration {
+	if m.phase == PhaseIdle {
+		return 0
+	}
+	return m.durationOf(m.phase) + m.extension
+}
+
+func (m *Machine) durationOf(p Phase) time.Duration {
+	switch p {
+	case PhaseWork:
+		return m.settings.Work
+	case PhaseShortBreak:
+		return m.settings.ShortBreak
+	case PhaseLongBreak:
+		return m.settings.LongBreak
+	default:
+		return 0
+	}
+}
+
+// Elapsed returns how long the current phase has been running, pauses excluded.
+func (m *Machine) Elapsed() time.Duration {
+	if m.phase == PhaseIdle {
+		return 0
+	}
+	now := m.clock.Now()
+	elapsed := now.Sub(m.phaseStart) - m.paused
+	if m.Paused() {
+		elapsed -= now.Sub(m.pausedAt)
+	}
+	return elapsed
+}
+
+// Remaining returns the time left in the current phase, never negative.
+func (m *Machine) Remaining() time.Duration {
+	remaining := m.Duration() - m.Elapsed()
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Expired reports whether the current phase has run for its whole duration.
+func (m *Machine) Expired() bool {
+	return m.phase != PhaseIdle && m.Remaining() == 0
+}
+
+// Paused reports whether the current phase is paused.
+func (m *Machine) Paused() bool {
+	return !m.pausedAt.IsZero()
+}
+
+// Pause stops the countdown of the current phase until Resume.
+func (m *Machine) Pause() {
+	if m.phase != PhaseIdle && !m.Paused() {
+		m.pausedAt = m.clock.Now()
+	}
+}
+
+// Resume restarts the countdown of a paused phase.
+func (m *Machine) Resume() {
+	if m.Paused() {
+		m.paused += m.clock.Now().Sub(m.pausedAt)
+		m.pausedAt = time.Time{}
+	}
+}
+
+// TogglePause pauses a running phase and resumes a paused one.
+func (m *Machine) TogglePause() {
+	if m.Paused() {
+		m.Resume()
+	} else {
+		m.Pause()
+	}
+}
+
+// Extend lengthens the current phase by d, or shortens it when d is negative. A phase
+// cannot be shortened below the time it has already run, which ends it.
+func (m *Machine) Extend(d time.Duration) {
+	if m.phase == PhaseIdle {
+		return
+	}
+	m.extension += d
+	if min := m.Elapsed() - m.durationOf(m.phase); m.extension < min {
+		m.extension = min
+	}
+}
+
+// Next returns the phase Advance starts: a work session after a break or when idle, and
+// after a work session a long break every LongBreakInterval sessions, a short one otherwise.
+func (m *Machine) Next() Phase {
+	if m.last != PhaseWork {
+		return PhaseWork
+	}
+	if m.completedWork > 0 && m.completedWork%m.settings.LongBreakInterval == 0 {
+		return PhaseLongBreak
+	}
+	return PhaseShortBreak
+}
+
+// Advance starts the next phase and returns it.
+func (m *Machine) Advance() Phase {
+	m.phase = m.Next()
+	m.phaseStart = m.clock.Now()
+	m.extension, m.paused, m.pausedAt = 0, 0, time.Time{}
+	return m.phase
+}
+
+// Complete ends the current phase as finished and returns its outcome. The machine is
+// idle until the next Advance.
+func (m *Machine) Complete() Outcome {
+	return m.finish(true)
+}
+
+// Interrupt ends the current phase before its end, e.g. when it is skipped or aborted,
+// and returns its outcome. An interrupted work session does not count towards the next
+// long break.
+func (m *Machine) Interrupt() Outcome {
+	return m.finish(false)
+}
+
+// finish ends the current phase and returns its outcome.
+func (m *Machine) finish(completed bool) Outcome {
+	outcome := Outcome{
+		Phase:     m.phase,
+		Start:     m.phaseStart,
+		End:       m.clock.Now(),
+		Elapsed:   m.Elapsed(),
+		Completed: completed,
+	}
+	switch {
+	case m.phase == PhaseWork && completed:
+		m.completedWork++
+	case m.phase == PhaseLongBreak:
+		m.completedWork = 0
+	}
+	if m.phase != PhaseIdle {
+		m.last = m.phase
+	}
+	m.phase = PhaseIdle
+	m.pausedAt = time.Time{}
+	return outcome
+}
@@ -0,0 +1,168 @@
+package pomodoro
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestMachine(interval int) (*Machine, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)}
+	settings := Settings{Work: 25 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, LongBreakInterval: interval}
+	return NewMachine(settings, clock), clock
+}
+
+// runToEnd advances m to its next phase, runs it until it expires and completes it.
+func runToEnd(t *testing.T, m *Machine, clock *fakeClock) Outcome {
+	t.Helper()
+	m.Advance()
+	clock.advance(m.Duration())
+	if !m.Expired() {
+		t.Fatalf("%s not expired after its duration", m.Phase())
+	}
+	return m.Complete()
+}
+
+func TestMachineLongBreakEveryInterval(t *testing.T) {
+	m, clock := newTestMachine(3)
+	want := []Phase{
+		PhaseWork, PhaseShortBreak, PhaseWork, PhaseShortBreak, PhaseWork, PhaseLongBreak,
+		PhaseWork, PhaseShortBreak, PhaseWork, PhaseShortBreak, PhaseWork, PhaseLongBreak,
+		PhaseWork,
+	}
+	for i, phase := range want {
+		if next := m.Next(); next != phase {
+			t.Fatalf("phase %d: Next() = %s, want %s", i, next, phase)
+		}
+		outcome := runToEnd(t, m, clock)
+		if outcome.Phase != phase || !outcome.Completed || outcome.Elapsed != m.durationOf(phase) {
+			t.Fatalf("phase %d: outcome %+v", i, outcome)
+		}
+	}
+	if got := m.CompletedInCycle(); got != 1 {
+		t.Errorf("CompletedInCycle() = %d, want 1", got)
+	}
+}
+
+func TestMachineInterruptedWorkDoesNotCount(t *testing.T) {
+	m, clock := newTestMachine(2)
+	runToEnd(t, m, clock) // work
+	runToEnd(t, m, clock) // short break
+
+	m.Advance()
+	clock.advance(10 * time.Minute)
+	outcome := m.Interrupt()
+	if outcome.Completed || outcome.Elapsed != 10*time.Minute {
+		t.Errorf("interrupted outcome %+v", outcome)
+	}
+	if got := m.CompletedInCycle(); got != 1 {
+		t.Errorf("CompletedInCycle() = %d after an interrupted Pomodoro, want 1", got)
+	}
+	// Skipping the work session still leads to a break, but a short one.
+	if next := m.Next(); next != PhaseShortBreak {
+		t.Errorf("Next() = %s, want %s", next, PhaseShortBreak)
+	}
+	runToEnd(t, m, clock)
+	runToEnd(t, m, clock) // work, the second completed one
+	if next := m.Next(); next != PhaseLongBreak {
+		t.Errorf("Next() = %s after the second completed Pomodoro, want %s", next, PhaseLongBreak)
+	}
+}
+
+func TestMachineInterruptedLongBreakResetsCycle(t *testing.T) {
+	m, clock := newTestMachine(1)
+	runToEnd(t, m, clock) // work
+	m.Advance()           // long break
+	clock.advance(time.Minute)
+	m.Interrupt()
+	if got := m.CompletedInCycle(); got != 0 {
+		t.Errorf("CompletedInCycle() = %d after a long break, want 0", got)
+	}
+}
+
+func TestMachinePauseResume(t *testing.T) {
+	m, clock := newTestMachine(4)
+	m.Advance()
+	clock.advance(5 * time.Minute)
+
+	m.Pause()
+	if !m.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+	clock.advance(7 * time.Minute)
+	if got := m.Elapsed(); got != 5*time.Minute {
+		t.Errorf("Elapsed() = %s while paused, want 5m0s", got)
+	}
+	m.Pause() // pausing twice keeps the first pause
+	clock.advance(3 * time.Minute)
+	m.Resume()
+	if m.Paused() {
+		t.Fatal("Paused() = true after Resume")
+	}
+	clock.advance(2 * time.Minute)
+	if got := m.Elapsed(); got != 7*time.Minute {
+		t.Errorf("Elapsed() = %s after a 10 minute pause, want 7m0s", got)
+	}
+	if got := m.Remaining(); got != 18*time.Minute {
+		t.Errorf("Remaining() = %s, want 18m0s", got)
+	}
+
+	m.TogglePause()
+	clock.advance(time.Hour)
+	if m.Expired() {
+		t.Error("a paused phase expired")
+	}
+	m.TogglePause()
+	clock.advance(18 * time.Minute)
+	outcome := m.Complete()
+	if outcome.Elapsed != 25*time.Minute || outcome.End.Sub(outcome.Start) != 25*time.Minute+70*time.Minute {
+		t.Errorf("outcome %+v", outcome)
+	}
+}
+
+func TestMachineExtend(t *testing.T) {
+	m, clock := newTestMachine(4)
+	m.Extend(time.Minute)
+	if m.Duration() != 0 {
+		t.Errorf("Duration() = %s when idle, want 0", m.Duration())
+	}
+
+	m.Advance()
+	clock.advance(10 * time.Minute)
+	m.Extend(5 * time.Minute)
+	if got := m.Duration(); got != 30*time.Minute {
+		t.Errorf("Duration() = %s after extending by 5m, want 30m0s", got)
+	}
+	m.Extend(-10 * time.Minute)
+	if got := m.Remaining(); got != 10*time.Minute {
+		t.Errorf("Remaining() = %s after shortening by 10m, want 10m0s", got)
+	}
+
+	// Shortening below the elapsed time ends the phase without going back in time.
+	m.Extend(-time.Hour)
+	if got := m.Duration(); got != 10*time.Minute {
+		t.Errorf("Duration() = %s after shortening by an hour, want the 10m0s elapsed", got)
+	}
+	if !m.Expired() || m.Remaining() != 0 {
+		t.Errorf("Expired() = %t, Remaining() = %s after shortening by an hour", m.Expired(), m.Remaining())
+	}
+	m.Extend(2 * time.Minute)
+	if got := m.Remaining(); got != 2*time.Minute {
+		t.Errorf("Remaining() = %s after extending an expired phase, want 2m0s", got)
+	}
+
+	// Extensions only last for their phase.
+	m.Complete()
+	m.Advance()
+	if got := m.Duration(); got != 5*time.Minute {
+		t.Errorf("Duration() = %s of the next break, want 5m0s", got)
+	}
+}
@@ -0,0 +1,337 @@
+// Package query implements the filter expressions accepted by `tasky list` and other
+// commands that operate on several tasks, e.g. `status:todo issue:>100 tag:backend login`.
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tasky/config"
+	"tasky/task"
+)
+
+// Field kinds decide how a term's value is compared.
+const (
+	kindText = iota
+	kindNumber
+	kindDate
+	kindTags
+)
+
+// fields lists every field usable in a term or a sort key.
+var fields = map[string]int{
+	"id":        kindText,
+	"title":     kindText,
+	"status":    kindText,
+	"project":   kindText,
+	"path":      kindText,
+	"branch":    kindText,
+	"issue":     kindNumber,
+	"pomodoros": kindNumber,
+	"duration":  kindNumber,
+	"created":   kindDate,
+	"started":   kindDate,
+	"done":      kindDate,
+	"tag":       kindTags,
+}
+
+// operators are tried longest first so that ">=" is not read as ">".
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// term is a single condition. An empty field means free text matched against the
+// title and the description.
+type term struct {
+	field  string
+	op     string
+	value  string
+	negate bool
+}
+
+// Query is a parsed filter expression. Terms are combined with AND.
+type Query struct {
+	terms []term
+}
+
+// Parse parses a filter expression. Terms are separated by spaces and may be quoted;
+// a leading '-' negates a term. Field terms look like `field:value` or `field:>value`
+// with one of the operators =, !=, >, >=, <, <=.
+func Parse(expr string) (*Query, error) {
+	words, err := splitWords(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{}
+	for _, word := range words {
+		t := term{}
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			t.negate = true
+			word = word[1:]
+		}
+
+		name, rest, hasField := strings.Cut(word, ":")
+		if _, known := fields[strings.ToLower(name)]; !hasField || !known {
+			t.value = strings.ToLower(word)
+			q.terms = append(q.terms, t)
+			continue
+		}
+
+		t.field = strings.ToLower(name)
+		t.op = "="
+		for _, op := range operators {
+			if strings.HasPrefix(rest, op) {
+				t.op = op
+				rest = rest[len(op):]
+				break
+			}
+		}
+		t.value = rest
+		if err := validateTerm(t); err != nil {
+			return nil, err
+		}
+		q.terms = append(q.terms, t)
+	}
+	return q, nil
+}
+
+// splitWords splits expr on spaces, keeping double-quoted sections together.
+func splitWords(expr string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inQuotes := false
+	hasWord := false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasWord = true
+		case r == ' ' && !inQuotes:
+			if hasWord {
+				words = append(words, current.String())
+				current.Reset()
+				hasWord = false
+			}
+		default:
+			current.WriteRune(r)
+			hasWord = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in query '%s'", expr)
+	}
+	if hasWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+func validateTerm(t term) error {
+	switch fields[t.field] {
+	case kindNumber:
+		if _, err := strconv.Atoi(t.value); err != nil {
+			return fmt.Errorf("invalid number '%s' for %s", t.value, t.field)
+		}
+	case kindDate:
+		if _, err := parseDateValue(t.value, time.Now()); err != nil {
+			return err
+		}
+	case kindTags:
+		if t.op != "=" && t.op != "!=" {
+			return fmt.Errorf("operator '%s' is not supported for tag", t.op)
+		}
+	}
+	return nil
+}
+
+// NeedsDescription reports whether matching requires the tasks' descriptions, i.e.
+// whether the query contains free text.
+func (q *Query) NeedsDescription() bool {
+	for _, t := range q.terms {
+		if t.field == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasField reports whether the query has a term on the given field.
+func (q *Query) HasField(field string) bool {
+	for _, t := range q.terms {
+		if t.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether an entry satisfies every term of the query.
+func (q *Query) Match(e task.Entry) bool {
+	for _, t := range q.terms {
+		if t.matches(e) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the entries matching the query, in their original order.
+func (q *Query) Filter(entries []task.Entry) []task.Entry {
+	var matched []task.Entry
+	for _, e := range entries {
+		if q.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+func (t term) matches(e task.Entry) bool {
+	if t.field == "" {
+		return strings.Contains(strings.ToLower(e.Title), t.value) ||
+			strings.Contains(strings.ToLower(e.Description), t.value)
+	}
+
+	switch fields[t.field] {
+	case kindNumber:
+		want, _ := strconv.Atoi(t.value)
+		return compare(numberValue(e, t.field)-want, t.op)
+	case kindDate:
+		got, ok := dateValue(e, t.field)
+		if !ok {
+			return false
+		}
+		want, _ := parseDateValue(t.value, time.Now())
+		return compare(got.Compare(want), t.op)
+	case kindTags:
+		want := strings.ToLower(strings.TrimPrefix(t.value, "#"))
+		has := slices.ContainsFunc(e.Tags, func(tag string) bool {
+			return strings.ToLower(strings.TrimPrefix(tag, "#")) == want
+		})
+		return has == (t.op == "=")
+	default:
+		got := strings.ToLower(textValue(e, t.field))
+		want := strings.ToLower(t.value)
+		if t.field == "status" {
+			want = NormalizeStatus(want)
+		}
+		if t.op == "=" || t.op == "!=" {
+			var equal bool
+			switch t.field {
+			case "title", "path":
+				equal = strings.Contains(got, want)
+			case "id":
+				equal = strings.HasPrefix(got, want)
+			default:
+				equal = got == want
+			}
+			return equal == (t.op == "=")
+		}
+		return compare(strings.Compare(got, want), t.op)
+	}
+}
+
+// compare turns the sign of a comparison into the result of op.
+func compare(cmp int, op string) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// NormalizeStatus maps the spellings accepted on the command line to a status value.
+func NormalizeStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "doing", "wip", "in-progress", "inprogress", "in_progress", "started":
+		return config.StatusInProgress
+	case "open":
+		return config.StatusTodo
+	default:
+		return strings.ToLower(status)
+	}
+}
+
+func textValue(e task.Entry, field string) string {
+	switch field {
+	case "id":
+		return e.ID
+	case "title":
+		return e.Title
+	case "status":
+		return e.Status
+	case "project":
+		return e.Project
+	case "path":
+		return e.Path
+	case "branch":
+		return e.Branch
+	}
+	return ""
+}
+
+func numberValue(e task.Entry, field string) int {
+	switch field {
+	case "issue":
+		return e.Issue
+	case "pomodoros":
+		return e.PomodoroCount
+	case "duration":
+		return e.Duration
+	}
+	return 0
+}
+
+// dateValue returns the day of a date field, or false when the task has no such date.
+func dateValue(e task.Entry, field string) (time.Time, bool) {
+	var raw string
+	switch field {
+	case "created":
+		raw = e.CreatedDate
+	case "started":
+		raw = e.StartDate
+	case "done":
+		raw = e.DoneDate
+	}
+	if len(raw) < len(config.DateLayout) {
+		return time.Time{}, false
+	}
+	day, err := time.ParseInLocation(config.DateLayout, raw[:len(config.DateLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// parseDateValue parses the date of a term: YYYY-MM-DD, today, yesterday, or a number
+// of days ago such as 7d.
+func parseDateValue(value string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	switch strings.ToLower(value) {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	if days, ok := strings.CutSuffix(strings.ToLower(value), "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return today.AddDate(0, 0, -n), nil
+		}
+	}
+	day, err := time.ParseInLocation(config.DateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s', expected YYYY-MM-DD, today, yesterday or <n>d", value)
+	}
+	return day, nil
+}
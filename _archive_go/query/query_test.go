@@ -0,0 +1,184 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tasky/config"
+	"tasky/task"
+)
+
+// testEntries returns tasks whose dates are relative to now where a term uses today,
+// yesterday or a number of days.
+func testEntries() []task.Entry {
+	now := time.Now()
+	return []task.Entry{
+		{
+			Task: config.Task{Frontmatter: config.Frontmatter{
+				ID: "abc123", Title: "Fix login bug", Status: config.StatusTodo, Issue: 101,
+				Tags: config.Tags{"backend", "#urgent"}, CreatedDate: "2024-01-10 09:00:00",
+				PomodoroCount: 2, Duration: 50,
+			}},
+			Project: "web", Path: "fix-login.md", Description: "Users cannot sign in.",
+		},
+		{
+			Task: config.Task{Frontmatter: config.Frontmatter{
+				ID: "abd456", Title: "Add search", Status: config.StatusInProgress, Issue: 42,
+				Tags: config.Tags{"frontend"}, CreatedDate: "2024-02-01 10:00:00",
+				StartDate: now.Format(config.DateTimeLayout), Branch: "feature/42-add-search",
+			}},
+			Project: "web", Path: "add-search.md",
+		},
+		{
+			Task: config.Task{Frontmatter: config.Frontmatter{
+				ID: "xyz789", Title: "Write docs", Status: config.StatusDone,
+				CreatedDate: "2024-01-10 18:30:00", DoneDate: now.AddDate(0, 0, -1).Format(config.DateLayout),
+				PomodoroCount: 5,
+			}},
+			Project: "docs", Path: "write-docs.md",
+		},
+	}
+}
+
+func ids(entries []task.Entry) []string {
+	ids := []string{}
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		// Text fields
+		{"status:todo", []string{"abc123"}},
+		{"status:doing", []string{"abd456"}},
+		{"status:in_progress", []string{"abd456"}},
+		{"status:!=done", []string{"abc123", "abd456"}},
+		{"-status:done", []string{"abc123", "abd456"}},
+		{"title:LOGIN", []string{"abc123"}},
+		{`title:"add search"`, []string{"abd456"}},
+		{`"title:add search"`, []string{"abd456"}},
+		{"title:>m", []string{"xyz789"}},
+		{"id:ab", []string{"abc123", "abd456"}},
+		{"id:bc", []string{}},
+		{"project:web", []string{"abc123", "abd456"}},
+		{"project:we", []string{}},
+		{"path:search", []string{"abd456"}},
+		{"branch:feature/42-add-search", []string{"abd456"}},
+		// Numbers
+		{"issue:42", []string{"abd456"}},
+		{"issue:>100", []string{"abc123"}},
+		{"issue:>=42 issue:<=100", []string{"abd456"}},
+		{"issue:<42", []string{"xyz789"}},
+		{"issue:!=42", []string{"abc123", "xyz789"}},
+		{"pomodoros:>=2", []string{"abc123", "xyz789"}},
+		{"duration:50", []string{"abc123"}},
+		// Dates compare days in DateLayout, whatever the time
+		{"created:2024-01-10", []string{"abc123", "xyz789"}},
+		{"created:>2024-01-10", []string{"abd456"}},
+		{"created:<2024-02-01", []string{"abc123", "xyz789"}},
+		{"created:>=2024-02-01", []string{"abd456"}},
+		{"started:today", []string{"abd456"}},
+		{"done:yesterday", []string{"xyz789"}},
+		{"done:>=7d", []string{"xyz789"}},
+		{"done:<yesterday", []string{}},
+		{"-done:today", []string{"abc123", "abd456", "xyz789"}},
+		// Tags
+		{"tag:backend", []string{"abc123"}},
+		{"tag:#Backend", []string{"abc123"}},
+		{"tag:urgent", []string{"abc123"}},
+		{"tag:!=backend", []string{"abd456", "xyz789"}},
+		// Free text, in the title or the description
+		{"fix bug", []string{"abc123"}},
+		{`"sign in"`, []string{"abc123"}},
+		{"-search", []string{"abc123", "xyz789"}},
+		{"unknown:field", []string{}},
+		{"", []string{"abc123", "abd456", "xyz789"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := ids(q.Filter(testEntries())); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"issue:abc", "invalid number 'abc' for issue"},
+		{"pomodoros:>", "invalid number '' for pomodoros"},
+		{"created:>soon", "invalid date 'soon'"},
+		{"done:2024-13-01", "invalid date '2024-13-01'"},
+		{"tag:>backend", "operator '>' is not supported for tag"},
+		{`title:"fix login`, "unterminated quote"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): got %v, want an error containing %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestQueryFields(t *testing.T) {
+	q, err := Parse("status:todo -tag:backend login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.HasField("status") || !q.HasField("tag") || q.HasField("issue") {
+		t.Errorf("HasField does not match the terms of the query")
+	}
+	if !q.NeedsDescription() {
+		t.Errorf("free text needs the description")
+	}
+	if q, _ := Parse("status:todo"); q.NeedsDescription() {
+		t.Errorf("field terms do not need the description")
+	}
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"issue", []string{"xyz789", "abd456", "abc123"}},
+		{"-pomodoros", []string{"xyz789", "abc123", "abd456"}},
+		{"project,title", []string{"xyz789", "abd456", "abc123"}},
+		{"created", []string{"abc123", "xyz789", "abd456"}},
+		{"-done", []string{"xyz789", "abc123", "abd456"}},       // tasks without the date stay in order
+		{"STATUS, -id", []string{"xyz789", "abd456", "abc123"}}, // "done" < "in progress" < "todo"
+		{"", []string{"abc123", "abd456", "xyz789"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			keys, err := ParseSort(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSort: %v", err)
+			}
+			entries := testEntries()
+			Sort(entries, keys)
+			if got := ids(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, spec := range []string{"tag", "nope", "issue,-"} {
+		if _, err := ParseSort(spec); err == nil {
+			t.Errorf("ParseSort(%q) accepted an invalid key", spec)
+		}
+	}
+}
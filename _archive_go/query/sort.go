@@ -0,0 +1,77 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"tasky/task"
+)
+
+// SortKey orders entries by one field. Keys given as "-field" sort in descending order.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma separated list of sort keys, e.g. "status,-created".
+func ParseSort(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := SortKey{}
+		if name, ok := strings.CutPrefix(part, "-"); ok {
+			key.Desc = true
+			part = name
+		}
+		key.Field = strings.ToLower(part)
+		if kind, known := fields[key.Field]; !known || kind == kindTags {
+			return nil, fmt.Errorf("cannot sort by '%s'", part)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Sort orders entries in place by keys, keeping the original order between equal entries.
+func Sort(entries []task.Entry, keys []SortKey) {
+	slices.SortStableFunc(entries, func(a, b task.Entry) int {
+		for _, key := range keys {
+			cmp := compareField(a, b, key.Field)
+			if key.Desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp
+			}
+		}
+		return 0
+	})
+}
+
+func compareField(a, b task.Entry, field string) int {
+	switch fields[field] {
+	case kindNumber:
+		return numberValue(a, field) - numberValue(b, field)
+	case kindDate:
+		// Dates keep their full precision; tasks without the date sort first.
+		return strings.Compare(rawDate(a, field), rawDate(b, field))
+	default:
+		return strings.Compare(strings.ToLower(textValue(a, field)), strings.ToLower(textValue(b, field)))
+	}
+}
+
+func rawDate(e task.Entry, field string) string {
+	switch field {
+	case "created":
+		return e.CreatedDate
+	case "started":
+		return e.StartDate
+	case "done":
+		return e.DoneDate
+	}
+	return ""
+}
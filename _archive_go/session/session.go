@@ -0,0 +1,156 @@
+// Package session keeps the append-only log of Pomodoro sessions. Every work session and
+// break is recorded, finished or not, and the Pomodoro statistics of a task are derived
+// from the log rather than counted separately.
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// Kind is the type of a session.
+type Kind string
+
+const (
+	KindWork       Kind = "work"
+	KindShortBreak Kind = "short_break"
+	KindLongBreak  Kind = "long_break"
+	// KindBaseline carries the Pomodoro count and duration a task had before the log
+	// existed, so that deriving statistics from the log does not reset them.
+	KindBaseline Kind = "baseline"
+)
+
+// Session is one record of the log.
+type Session struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Kind      Kind      `json:"kind"`
+	Project   string    `json:"project,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Completed bool      `json:"completed"`
+	// Elapsed is the time actually spent, which differs from End-Start when the session was paused.
+	Elapsed time.Duration `json:"elapsed_ns"`
+	// Pomodoros is only set on baseline records.
+	Pomodoros int `json:"pomodoros,omitempty"`
+}
+
+// LogPath returns the location of the session log: the configured one, or
+// sessions.ndjson in the user's data directory.
+func LogPath(cfg config.Config) (string, error) {
+	if cfg.Pomodoro.SessionLog != "" {
+		return cfg.Pomodoro.SessionLog, nil
+	}
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "tasky", "sessions.ndjson"), nil
+}
+
+// Lock takes the lock of the session log, held by whoever reads the log to derive what
+// it appends, such as a task's totals, so that two tasky processes recording at once do
+// not work from stale data. It returns the function releasing the lock.
+func Lock(cfg config.Config) (func(), error) {
+	path, err := LogPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return utils.LockFile(path + ".lock")
+}
+
+// Append adds a session at the end of the log.
+func Append(cfg config.Config, s Session) error {
+	path, err := LogPath(cfg)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open session log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not write session log: %w", err)
+	}
+	return nil
+}
+
+// Read returns every session of the log, oldest first. A missing log is empty.
+func Read(cfg config.Config) ([]Session, error) {
+	path, err := LogPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open session log: %w", err)
+	}
+	defer f.Close()
+
+	var sessions []Session
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			return nil, fmt.Errorf("invalid session log entry at %s:%d: %w", path, lineNumber, err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, scanner.Err()
+}
+
+// ForTask returns the sessions recorded for a task.
+func ForTask(sessions []Session, project, taskID string) []Session {
+	var matched []Session
+	for _, s := range sessions {
+		if s.TaskID == taskID && s.Project == project {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// Totals derives a task's Pomodoro statistics from its sessions: the number of completed
+// work sessions and the minutes spent working, interrupted sessions included.
+func Totals(sessions []Session) (pomodoros int, minutes int) {
+	var worked time.Duration
+	for _, s := range sessions {
+		switch s.Kind {
+		case KindBaseline:
+			pomodoros += s.Pomodoros
+			worked += s.Elapsed
+		case KindWork:
+			if s.Completed {
+				pomodoros++
+			}
+			worked += s.Elapsed
+		}
+	}
+	return pomodoros, int(worked.Round(time.Minute) / time.Minute)
+}
@@ -1,103 +1,113 @@
 package task
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"regexp"
-	"strings"
+	"slices"
 	"time"
 
 	"tasky/config"
+	"tasky/forge"
 	"tasky/utils"
 )
 
-func CreateTask(cfg config.Config, title, description string, createGitHubIssue bool) (string, string, error) {
+// CreateOptions tunes Service.Create.
+type CreateOptions struct {
+	Description string
+	// CreateIssue opens an issue for the task on the forge of the repository.
+	CreateIssue bool
+	// CreateProject allows creating the first task of a project.
+	CreateProject bool
+}
+
+// Created is a task created by Service.Create.
+type Created struct {
+	Entry
+	// Issue is the issue opened for the task, if any.
+	Issue *forge.Issue
+}
+
+// Create writes a new task note for the current project, optionally opening an issue
+// for it on the forge of the repository. The returned task carries its generated ID.
+// Unless opts.CreateProject is set, a project without tasks yields an error wrapping
+// ErrNotFound.
+func (s *Service) Create(title string, opts CreateOptions) (*Created, error) {
 	task := config.Task{
 		Frontmatter: config.Frontmatter{
-			Title:		title,
-			Status:		config.StatusTodo,
-			CreatedDate: time.Now().Format("2006-01-02 15:04:05"),
+			Title:       title,
+			Status:      config.StatusTodo,
+			CreatedDate: time.Now().Format(config.DateTimeLayout),
 		},
 	}
 
-	projectName := utils.GetProjectName()
+	projectName := utils.GetProjectName(s.cfg)
 
-	// Check if project directory exists in VaultPath
-	projectDir := filepath.Join(cfg.General.VaultPath, projectName)
-	if _, err := os.Stat(projectDir); os.IsNotExist(err) {
-		fmt.Printf("Project directory '%s' does not exist. Create it? (Y/n): ", projectDir)
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		trimmedInput := strings.ToLower(strings.TrimSpace(input))
-		if trimmedInput == "y" || trimmedInput == "" {
-			if err := os.MkdirAll(projectDir, 0755); err != nil {
-				return "", "", fmt.Errorf("could not create project directory: %w", err)
-			}
-			fmt.Printf("Project directory '%s' created.\n", projectDir)
-		} else {
-			return "", "", fmt.Errorf("project directory '%s' does not exist", projectDir)
-		}
+	// Check if the project already exists in the task store
+	projects, err := ListProjects(s.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not list projects: %w", err)
+	}
+	if !opts.CreateProject && !slices.Contains(projects, projectName) {
+		return nil, fmt.Errorf("project '%s' %w", projectName, ErrNotFound)
 	}
 
-	// Format filename
-	safeTitle := strings.ReplaceAll(strings.ToLower(title), " ", "-")
-	baseFilename := fmt.Sprintf("%s.md", safeTitle)
-	filename := baseFilename
-	filePath := filename
-
-	var createdIssueNumber string
-
-	// Create GitHub issue if requested and possible
-	if createGitHubIssue && utils.IsGitRepository() && utils.HasGitHubRemote() {
-		cmd := exec.Command("gh", "issue", "create", "--title", title, "--body", description)
-		output, err := cmd.CombinedOutput()
+	// Create the issue if requested
+	var issue *forge.Issue
+	if opts.CreateIssue {
+		f, err := forge.New(s.cfg)
 		if err != nil {
-			return "", "", fmt.Errorf("error creating GitHub issue: %w\n%s", err, string(output))
+			return nil, fmt.Errorf("cannot create an issue: %w", err)
 		}
-		fmt.Println("GitHub issue created successfully.")
-		fmt.Println(string(output))
-
-		// Extract issue number from gh output
-		outputStr := string(output)
-		re := regexp.MustCompile(`https://github.com/.*/issues/([0-9]+)`)
-		matches := re.FindStringSubmatch(outputStr)
-		if len(matches) > 1 {
-			issueNumber := 0
-			fmt.Sscanf(matches[1], "%d", &issueNumber)
-			createdIssueNumber = matches[1]
-			task.Issue = issueNumber // Update task struct with issue number
+		created, err := f.CreateIssue(title, opts.Description)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s issue: %w", f.Name(), err)
 		}
+		issue = &created
+		task.Issue = issue.Number
 	}
 
-	// If an issue was created, update filename and filePath
-	if createdIssueNumber != "" {
-		filename = fmt.Sprintf("%s-%s", createdIssueNumber, baseFilename)
-				filePath = filename
+	filePath, err := CreateTaskNote(s.cfg, projectName, &task, opts.Description)
+	if err != nil {
+		return nil, err
 	}
+	return &Created{Entry: Entry{Task: task, Project: projectName, Path: filePath}, Issue: issue}, nil
+}
 
-	// Check for existing file and add a number if necessary (after potential issue number prefix)
-	for i := 1; ; i++ {
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			break
-		}
-		// If issue number was added, ensure it's preserved in subsequent attempts
-		if createdIssueNumber != "" {
-			filename = fmt.Sprintf("%s-%s-%d.md", createdIssueNumber, safeTitle, i)
-		} else {
-			filename = fmt.Sprintf("%s-%d.md", safeTitle, i)
-		}
-				filePath = filename
+// CreateTaskNote assigns a new ID to task and writes it as a new note of projectName,
+// without any prompt. It returns the note's path in the task store.
+func CreateTaskNote(cfg config.Config, projectName string, task *config.Task, description string) (string, error) {
+	files, err := loadProjectTasks(cfg, projectName)
+	if err != nil {
+		return "", err
+	}
+	existingIDs := make(map[string]bool)
+	for _, f := range files {
+		existingIDs[f.Task.ID] = true
+	}
+	task.ID, err = NewID(existingIDs)
+	if err != nil {
+		return "", err
 	}
 
-	// Write initial file
-	if err := WriteTaskFile(cfg, projectName, filePath, &task, description); err != nil {
-		return "", "", fmt.Errorf("error creating file: %w", err)
+	// Name the note, holding the store's lock so that another tasky process cannot take
+	// the same name before the note is written
+	store, err := utils.OpenTaskStore(cfg, projectName)
+	if err != nil {
+		return "", fmt.Errorf("error opening task store: %w", err)
+	}
+	unlock, err := utils.LockStore(store)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	filePath, err := freeNoteName(store, noteFilename(cfg, task))
+	if err != nil {
+		return "", err
 	}
 
-	fmt.Printf("Task created: %s\n", filePath)
+	// Write initial file
+	if err := WriteTaskFile(cfg, projectName, filePath, task, description); err != nil {
+		return "", fmt.Errorf("error creating file: %w", err)
+	}
 
-	return createdIssueNumber, filePath, nil
-}
\ No newline at end of file
+	return filePath, nil
+}
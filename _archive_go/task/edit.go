@@ -0,0 +1,109 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// Set changes fields of the task of projectName matching ref, in order, and writes its
+// note. Invalid values yield an error wrapping ErrInvalid, and the note is left
+// untouched.
+func (s *Service) Set(projectName, ref string, fields []Field) (*Entry, error) {
+	_, foundPath, err := FindTask(s.cfg, projectName, ref)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := updateNote(s.cfg, projectName, foundPath, func(t *config.Task, description *string) error {
+		for _, f := range fields {
+			if err := applyField(t, description, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Task: *updated, Project: projectName, Path: foundPath}, nil
+}
+
+// ReadNote returns the task of projectName matching ref with the content of its note,
+// to edit it and pass it to SaveNote.
+func (s *Service) ReadNote(projectName, ref string) (*Entry, []byte, error) {
+	e, err := s.Find(projectName, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := utils.ReadFromTaskyFile(s.cfg, projectName, e.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading task file %s: %w", e.Path, err)
+	}
+	return e, content, nil
+}
+
+// SaveNote writes content, the edited version of the note of e read by ReadNote as
+// original. The frontmatter keys tasky owns are normalized as WriteTaskFile writes them,
+// and a changed status updates the start and done dates as Set does.
+// A note whose frontmatter cannot be read, or gives a task invalid fields or another ID,
+// yields an error wrapping ErrInvalid. If the note changed since it was read, nothing is
+// written.
+func (s *Service) SaveNote(e *Entry, original, content []byte) (*Entry, error) {
+	t, description, err := parseTaskContent(content, s.cfg.NoteFormat, e.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w note: %w", ErrInvalid, err)
+	}
+	if err := validateTask(t); err != nil {
+		return nil, err
+	}
+	if t.ID != e.ID {
+		return nil, fmt.Errorf("id: %w value '%s' (the ID of a task cannot change, it was '%s')", ErrInvalid, t.ID, e.ID)
+	}
+	normalizeStatus(t, &e.Task)
+	newContent, err := renderTaskContent(content, s.cfg.NoteFormat, t, description, e.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := utils.OpenTaskStore(s.cfg, e.Project)
+	if err != nil {
+		return nil, fmt.Errorf("error opening task store: %w", err)
+	}
+	unlock, err := utils.LockStore(store)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if !bytes.Equal(newContent, original) {
+		err = putIfUnchanged(store, e.Path, original, newContent)
+		if errors.Is(err, errNoteChanged) {
+			return nil, fmt.Errorf("error writing task file %s: %w while it was edited", e.Path, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing task file %s: %w", e.Path, err)
+		}
+	}
+	return &Entry{Task: *t, Project: e.Project, Path: e.Path, File: e.File}, nil
+}
+
+// normalizeStatus writes the status of an edited task t as tasky spells it, such as "in
+// progress" for "in_progress". When it differs from the status before the edit, the
+// start and done dates follow it as in applyStatus, unless they were edited too.
+func normalizeStatus(t *config.Task, before *config.Task) {
+	status, _ := parseStatus(t.Status)
+	if previous, _ := parseStatus(before.Status); status == previous {
+		t.Status = status
+		return
+	}
+	edited := *t
+	applyStatus(t, status)
+	if edited.StartDate != before.StartDate {
+		t.StartDate = edited.StartDate
+	}
+	if edited.DoneDate != before.DoneDate {
+		t.DoneDate = edited.DoneDate
+	}
+}
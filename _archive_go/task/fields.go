@@ -0,0 +1,170 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tasky/config"
+)
+
+// Field is a value given to a task field as text, as in "status=done".
+type Field struct {
+	Name  string
+	Value string
+}
+
+// SettableFields are the fields Service.Set changes. "body" is the markdown content of
+// the note.
+var SettableFields = []string{"title", "status", "issue", "branch", "tags", "created_date", "start_date", "done_date", "pomodoro_count", "duration", "body"}
+
+// ParseField parses a "name=value" argument.
+func ParseField(arg string) (Field, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return Field{}, fmt.Errorf("'%s' is %w: expected field=value", arg, ErrInvalid)
+	}
+	return Field{Name: strings.TrimSpace(name), Value: value}, nil
+}
+
+// applyField sets a field of t, or the note's content, from its text value.
+func applyField(t *config.Task, description *string, f Field) error {
+	value := strings.TrimSpace(f.Value)
+	switch f.Name {
+	case "title":
+		if value == "" {
+			return fieldError(f, "a task needs a title")
+		}
+		t.Title = value
+	case "status":
+		status, ok := parseStatus(value)
+		if !ok {
+			return fieldError(f, "expected todo, in progress or done")
+		}
+		return applyStatus(t, status)
+	case "issue":
+		issue, err := parseCount(strings.TrimPrefix(value, "#"))
+		if err != nil {
+			return fieldError(f, "expected an issue number, or nothing to unlink the issue")
+		}
+		t.Issue = issue
+	case "branch":
+		t.Branch = value
+	case "tags":
+		t.Tags = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+	case "created_date", "start_date", "done_date":
+		date, err := parseDate(value, f.Name == "done_date")
+		if err != nil {
+			return fieldError(f, err.Error())
+		}
+		switch f.Name {
+		case "created_date":
+			t.CreatedDate = date
+		case "start_date":
+			t.StartDate = date
+		default:
+			t.DoneDate = date
+		}
+	case "pomodoro_count", "duration":
+		n, err := parseCount(value)
+		if err != nil {
+			return fieldError(f, "expected a number")
+		}
+		if f.Name == "duration" {
+			t.Duration = n
+		} else {
+			t.PomodoroCount = n
+		}
+	case "body":
+		*description = strings.TrimSpace(f.Value)
+	default:
+		return fmt.Errorf("%w field '%s' (expected one of %s)", ErrInvalid, f.Name, strings.Join(SettableFields, ", "))
+	}
+	return nil
+}
+
+// fieldError reports an invalid value for f.
+func fieldError(f Field, reason string) error {
+	return fmt.Errorf("%s: %w value '%s' (%s)", f.Name, ErrInvalid, f.Value, reason)
+}
+
+// parseStatus returns the status named by value, accepting "in_progress" and
+// "in-progress" for "in progress".
+func parseStatus(value string) (string, bool) {
+	status := strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(value))
+	switch status {
+	case config.StatusTodo, config.StatusInProgress, config.StatusDone:
+		return status, true
+	}
+	return "", false
+}
+
+// parseCount parses a number that cannot be negative; "" is 0.
+func parseCount(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("negative number")
+	}
+	return n, err
+}
+
+// parseDate checks a date with or without a time, as tasks store them, and returns it
+// in the layout of its field: a date for done_date, a date and time for the others,
+// where a date alone is midnight.
+func parseDate(value string, dateOnly bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	for _, layout := range []string{config.DateTimeLayout, config.DateLayout} {
+		if d, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			if dateOnly {
+				return d.Format(config.DateLayout), nil
+			}
+			return d.Format(config.DateTimeLayout), nil
+		}
+	}
+	return "", fmt.Errorf("expected a date as %s or %s", config.DateLayout, config.DateTimeLayout)
+}
+
+// applyStatus moves t to status, keeping its start and done dates consistent with it.
+func applyStatus(t *config.Task, status string) error {
+	now := time.Now()
+	switch status {
+	case config.StatusTodo:
+		t.DoneDate = ""
+	case config.StatusInProgress:
+		if t.StartDate == "" {
+			t.StartDate = now.Format(config.DateTimeLayout)
+		}
+		t.DoneDate = ""
+	case config.StatusDone:
+		t.DoneDate = now.Format(config.DateLayout)
+	default:
+		return fmt.Errorf("unknown status '%s'", status)
+	}
+	t.Status = status
+	return nil
+}
+
+// validateTask checks the fields of a task read from an edited note.
+func validateTask(t *config.Task) error {
+	if strings.TrimSpace(t.Title) == "" {
+		return fmt.Errorf("title: %w: a task needs a title", ErrInvalid)
+	}
+	if _, ok := parseStatus(t.Status); !ok {
+		return fieldError(Field{Name: "status", Value: t.Status}, "expected todo, in progress or done")
+	}
+	for name, value := range map[string]string{"created_date": t.CreatedDate, "start_date": t.StartDate, "done_date": t.DoneDate} {
+		if _, err := parseDate(value, false); err != nil {
+			return fieldError(Field{Name: name, Value: value}, err.Error())
+		}
+	}
+	if t.PomodoroCount < 0 || t.Duration < 0 || t.Issue < 0 {
+		return fmt.Errorf("pomodoro_count, duration and issue: %w: must not be negative", ErrInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// separatorRun matches the separators left around an empty placeholder.
+var separatorRun = regexp.MustCompile(`([-_. ])[-_. ]+`)
+
+// noteFilename names the note of a new task after filename.template, without extension.
+func noteFilename(cfg config.Config, task *config.Task) string {
+	issue := ""
+	if task.Issue != 0 {
+		issue = strconv.Itoa(task.Issue)
+	}
+	date := time.Now().Format(config.DateLayout)
+	if created, err := time.Parse(config.DateTimeLayout, task.CreatedDate); err == nil {
+		date = created.Format(config.DateLayout)
+	}
+	name := strings.NewReplacer(
+		"{issue}", issue,
+		"{slug}", utils.Slug(task.Title, cfg.Filename.SlugLength()),
+		"{date}", date,
+		"{id}", task.ID,
+	).Replace(cfg.Filename.NameTemplate())
+	name = strings.Trim(separatorRun.ReplaceAllString(name, "$1"), "-_. ")
+	if name == "" {
+		// A title without letters nor digits, such as an emoji, and no other placeholder.
+		name = task.ID
+	}
+	return name
+}
+
+// freeNoteName returns base + ".md", or base-1.md, base-2.md... if the store already
+// holds that name. Names are compared case-folded, since Foo.md and foo.md are the same
+// file on macOS and Windows.
+func freeNoteName(store utils.TaskStore, base string) (string, error) {
+	names, err := store.List()
+	if err != nil {
+		return "", fmt.Errorf("error listing notes: %w", err)
+	}
+	taken := make(map[string]bool, len(names))
+	for _, name := range names {
+		taken[strings.ToLower(name)] = true
+	}
+	name := base + ".md"
+	for i := 1; taken[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d.md", base, i)
+	}
+	return name, nil
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"testing"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+func TestNoteFilename(t *testing.T) {
+	task := &config.Task{Frontmatter: config.Frontmatter{
+		ID: "abc123", Title: "Fix login bug", Issue: 42, CreatedDate: "2024-01-10 09:00:00",
+	}}
+	tests := []struct {
+		template string
+		issue    int
+		title    string
+		want     string
+	}{
+		{"", 42, "Fix login bug", "42-fix-login-bug"},
+		{"", 0, "Fix login bug", "fix-login-bug"},
+		{"{date}_{slug}", 0, "Fix login bug", "2024-01-10_fix-login-bug"},
+		{"{id} {slug}", 0, "Fix login bug", "abc123 fix-login-bug"},
+		{"{issue}-{slug}", 0, "🚀", "abc123"},
+		{"{slug}", 0, "aux", "aux-task"},
+	}
+	for _, tt := range tests {
+		cfg := config.Config{Filename: config.Filename{Template: tt.template}}
+		task.Issue, task.Title = tt.issue, tt.title
+		if got := noteFilename(cfg, task); got != tt.want {
+			t.Errorf("noteFilename(%q, %q) = %q, want %q", tt.template, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestFreeNoteName(t *testing.T) {
+	store := utils.NewMemoryStore()
+	for _, name := range []string{"fix-login.md", "Fix-Login-1.md", "add-search-1.md"} {
+		if err := store.Put(name, []byte("---\n---\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"write-docs", "write-docs.md"},
+		{"fix-login", "fix-login-2.md"},
+		{"FIX-LOGIN", "FIX-LOGIN-2.md"},
+		{"add-search", "add-search.md"},
+	}
+	for _, tt := range tests {
+		got, err := freeNoteName(store, tt.base)
+		if err != nil {
+			t.Fatalf("freeNoteName(%q): %v", tt.base, err)
+		}
+		if got != tt.want {
+			t.Errorf("freeNoteName(%q) = %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
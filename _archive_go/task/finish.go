@@ -1,117 +1,311 @@
 package task
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"io"
+	"strconv"
+	"strings"
 	"tasky/config"
+	"tasky/forge"
 	"tasky/utils"
 	"time"
 )
 
-// FinishTask closes a GitHub issue, merges the PR, and updates the task note.
-func FinishTask(cfg config.Config) error {
+// FinishOptions tunes Service.Finish.
+type FinishOptions struct {
+	// Out receives the progress messages, the commands of a dry run and the changes to
+	// the note; nil discards them.
+	Out io.Writer
+	// Confirm, when set, is asked before each step with its description; declining one
+	// stops there.
+	Confirm func(step string) bool
+	// DryRun prints the git and forge commands and the changes to the note instead of
+	// running and making them.
+	DryRun bool
+	// Strategy merges the pull request, one of forge.MergeSquash (the default),
+	// forge.MergeCommit and forge.MergeRebase.
+	Strategy string
+	// NoMerge only opens the pull request, leaving the task as it is. Draft pull
+	// requests are never merged.
+	NoMerge bool
+	Draft   bool
+	// KeepBranch keeps the branch after merging it.
+	KeepBranch bool
+	// Resume, when set, is asked whether to finish the branch left unfinished by an
+	// earlier run, when the current branch has no task. Without it, only the current
+	// branch is finished.
+	Resume func(branch string) bool
+}
+
+// ErrStopped is returned by Service.Finish when a step is declined.
+var ErrStopped = errors.New("stopped")
+
+// finishStep is one step of Service.Finish.
+type finishStep struct {
+	// description says what the step does, as in "Push branch x to origin".
+	description string
+	// done reports whether the step was already done, by an earlier run or by hand.
+	done func() bool
+	run  func() error
+}
+
+// Finish opens a pull request for the current branch on the forge of the repository,
+// closing the issue of the branch if it has one, merges it, and updates the task note.
+// Steps already done, such as a push or a pull request, are skipped, and the progress is
+// saved after each step that completes, so Finish can run again after failing halfway.
+// When the current branch has no task, as after a merge deleted it, the unfinished
+// branch of the repository can be resumed, see FinishOptions.Resume.
+func (s *Service) Finish(opts FinishOptions) error {
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = forge.MergeSquash
+	case forge.MergeSquash, forge.MergeCommit, forge.MergeRebase:
+	default:
+		return fmt.Errorf("unknown merge strategy '%s' (expected %s, %s or %s)", opts.Strategy, forge.MergeSquash, forge.MergeCommit, forge.MergeRebase)
+	}
+
 	// 1. Get current branch name
 	branchName, err := utils.GetCurrentBranchName()
 	if err != nil {
 		return fmt.Errorf("failed to get current branch name: %w", err)
 	}
 
-	// 2. Extract issue number from branch name
-	issueNumber := utils.ExtractIssueNumberFromBranch(branchName)
-	if issueNumber == "" {
-		fmt.Printf("No issue number found in branch name: %s\n", branchName)
-		return nil
+	// 2. Find the task linked to the branch and the issue number in its name
+	projectName := utils.GetProjectName(s.cfg)
+	if projectName == "unknown_project" {
+		return fmt.Errorf("could not determine project name. Please run this command in a Git repository")
 	}
-
-	fmt.Printf("Found GitHub issue number: %s in branch: %s\n", issueNumber, branchName)
-
-	// 3. GitHub operations: Push, Create PR and merge
-	fmt.Println("Pushing branch to remote...")
-	pushCmd := exec.Command("git", "push")
-	pushCmd.Stdout = utils.NewLogWriter("git push", false)
-	pushCmd.Stderr = utils.NewLogWriter("git push", true)
-	if err := pushCmd.Run(); err != nil {
-		return fmt.Errorf("failed to push branch: %w", err)
+	state := loadFinishState()
+	branchTask, issueNumber, err := findFinishTarget(s.cfg, projectName, branchName)
+	if err != nil {
+		return err
+	}
+	if resumed := state.unfinished(); issueNumber == "" && branchTask == nil && resumed != "" && resumed != branchName &&
+		opts.Resume != nil && opts.Resume(resumed) {
+		fmt.Fprintf(out, "Resuming finish of branch: %s\n", resumed)
+		branchName = resumed
+		if branchTask, issueNumber, err = findFinishTarget(s.cfg, projectName, branchName); err != nil {
+			return err
+		}
+	}
+	if issueNumber == "" && branchTask == nil {
+		return fmt.Errorf("issue number or task for branch %s %w", branchName, ErrNotFound)
 	}
 
-	fmt.Println("Creating GitHub pull request...")
-	// Get issue title
-	issueTitleCmd := exec.Command("gh", "issue", "view", issueNumber, "--json", "title")
-	issueTitleOutput, err := issueTitleCmd.Output()
+	f, err := forge.New(s.cfg)
 	if err != nil {
-		return fmt.Errorf("failed to get issue title: %w", err)
+		return err
+	}
+	if opts.DryRun {
+		f = forge.DryRun(f, out)
+	}
+	var prTitle, prBody string
+	if issueNumber != "" {
+		fmt.Fprintf(out, "Found %s issue number: %s in branch: %s\n", f.Name(), issueNumber, branchName)
+		number, _ := strconv.Atoi(issueNumber)
+		issue, err := f.ViewIssue(number)
+		if err != nil {
+			return fmt.Errorf("failed to get issue title: %w", err)
+		}
+		prTitle, prBody = issue.Title, "Closes #"+issueNumber
+	} else {
+		fmt.Fprintf(out, "Found task '%s' linked to branch: %s\n", branchTask.Title, branchName)
+		prTitle = branchTask.Title
 	}
 
-	// Parse JSON output to extract title
-	var result struct{ Title string }
-	if err := json.Unmarshal(issueTitleOutput, &result); err != nil {
-		return fmt.Errorf("failed to parse issue title JSON: %w", err)
+	// 3. Find what an earlier run, or someone else, already did
+	// The branch is only recorded once a step completes, so that a branch nothing was
+	// done for is not resumed later.
+	progress, tracked := state.Branches[branchName]
+	if !tracked {
+		progress = &finishProgress{}
 	}
-	prTitle := result.Title
+	pr := progress.PullRequest
+	existing, err := f.FindPullRequest(branchName)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s pull request: %w", f.Name(), err)
+	}
+	if existing != nil {
+		pr = existing
+		if pr.State == forge.StateClosed {
+			// A closed pull request was abandoned: open a new one.
+			pr = nil
+		}
+	}
+	merged := progress.Merged || pr != nil && pr.State == forge.StateMerged
 
-	fmt.Println("Executing: gh pr create --title \"" + prTitle + "\" --body \"Closes #" + issueNumber + "\"")
-	createPRCmd := exec.Command("gh", "pr", "create", "--title", prTitle, "--body", "Closes #"+issueNumber)
-	createPRCmd.Stdout = utils.NewLogWriter("gh pr create", false)
-	createPRCmd.Stderr = utils.NewLogWriter("gh pr create", true)
-	if err := createPRCmd.Run(); err != nil {
-		return fmt.Errorf("failed to create GitHub pull request: %w", err)
+	// 4. Forge operations: Push, Create PR and merge, then update the Markdown file
+	steps := []finishStep{
+		{
+			description: fmt.Sprintf("Push branch %s to origin", branchName),
+			done: func() bool {
+				return merged || isPushed(branchName)
+			},
+			run: func() error {
+				return pushBranch(out, branchName, opts.DryRun)
+			},
+		},
+		{
+			description: fmt.Sprintf("Create %s pull request '%s'", f.Name(), prTitle),
+			done: func() bool {
+				return pr != nil
+			},
+			run: func() error {
+				created, err := f.CreatePullRequest(prTitle, prBody, opts.Draft)
+				if err != nil {
+					return fmt.Errorf("failed to create %s pull request: %w", f.Name(), err)
+				}
+				if created.URL != "" {
+					fmt.Fprintln(out, created.URL)
+				}
+				created.Branch = branchName
+				pr = &created
+				progress.PullRequest = pr
+				return nil
+			},
+		},
 	}
+	if !opts.NoMerge && !opts.Draft {
+		description := fmt.Sprintf("Merge %s pull request (%s)", f.Name(), opts.Strategy)
+		if !opts.KeepBranch {
+			description += " and delete branch " + branchName
+		}
+		steps = append(steps, finishStep{
+			description: description,
+			done: func() bool {
+				return merged
+			},
+			run: func() error {
+				if err := f.MergePullRequest(*pr, opts.Strategy, !opts.KeepBranch); err != nil {
+					return fmt.Errorf("failed to merge %s pull request: %w", f.Name(), err)
+				}
+				merged = true
+				progress.Merged = true
+				return nil
+			},
+		})
 
-	fmt.Println("Merging GitHub pull request and deleting branch...")
-	// Explain the gh pr merge command
-	fmt.Println("Executing: gh pr merge -d -s")
-	mergePRCmd := exec.Command("gh", "pr", "merge", "-d", "-s")
-	mergePRCmd.Stdout = utils.NewLogWriter("gh pr merge", false)
-	mergePRCmd.Stderr = utils.NewLogWriter("gh pr merge", true)
-	if err := mergePRCmd.Run(); err != nil {
-		return fmt.Errorf("failed to merge GitHub pull request: %w", err)
+		noteTask, foundPath := branchTask, ""
+		if branchTask != nil {
+			foundPath = branchTask.Path
+		} else if t, path, err := FindTask(s.cfg, projectName, "#"+issueNumber); err != nil {
+			fmt.Fprintf(out, "No task note found with issue #%s: %v\n", issueNumber, err)
+		} else {
+			noteTask, foundPath = &Entry{Task: *t, Project: projectName, Path: path}, path
+		}
+		if foundPath != "" {
+			steps = append(steps, finishStep{
+				description: fmt.Sprintf("Mark %s done", foundPath),
+				done: func() bool {
+					return noteTask.Status == config.StatusDone
+				},
+				run: func() error {
+					return markNoteDone(out, s.cfg, projectName, foundPath, opts.DryRun)
+				},
+			})
+		}
 	}
 
-	// 4. Find and update Markdown file
-	projectName := utils.GetProjectName()
-	if projectName == "unknown_project" {
-		return fmt.Errorf("could not determine project name. Please run this command in a Git repository")
+	for _, step := range steps {
+		if step.done() {
+			fmt.Fprintln(out, step.description+": already done")
+			continue
+		}
+		if opts.Confirm != nil && !opts.Confirm(step.description) {
+			return fmt.Errorf("%w before: %s", ErrStopped, step.description)
+		}
+		fmt.Fprintln(out, step.description+"...")
+		if err := step.run(); err != nil {
+			return err
+		}
+		state.Branches[branchName] = progress
+		saveFinishState(out, state, opts.DryRun)
 	}
+	delete(state.Branches, branchName)
+	saveFinishState(out, state, opts.DryRun)
+	return nil
+}
 
-	projectTasksPath, err := utils.GetTaskyDir(cfg, projectName)
+// findFinishTarget returns the task linked to branch and the issue number in its name or
+// of that task, either being empty when there is none.
+func findFinishTarget(cfg config.Config, projectName, branch string) (*Entry, string, error) {
+	branchTask, err := findBranchTask(cfg, projectName, branch)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+	issueNumber := utils.ExtractIssueNumberFromBranch(cfg, branch)
+	if issueNumber == "" && branchTask != nil && branchTask.Issue != 0 {
+		issueNumber = strconv.Itoa(branchTask.Issue)
 	}
+	return branchTask, issueNumber, nil
+}
 
-	files, err := utils.ListMarkdownFiles(projectTasksPath)
-	if err != nil {
-		return fmt.Errorf("failed to list markdown files: %w", err)
+// saveFinishState saves the progress of FinishTask, except in a dry run. Failing to save
+// is not fatal: the next run detects most of what was done anyway.
+func saveFinishState(out io.Writer, state *finishState, dryRun bool) {
+	if dryRun {
+		return
 	}
+	if err := state.save(); err != nil {
+		fmt.Fprintf(out, "Warning: could not save the finish progress: %v\n", err)
+	}
+}
 
-	var foundTask *config.Task
-	var foundPath string
-	for _, file := range files {
-		t, _, err := ReadTaskFile(cfg, projectName, file)
-		if err == nil && fmt.Sprintf("%d", t.Issue) == issueNumber {
-			foundTask = t
-			foundPath = file
-			break
-		}
+// isPushed reports whether origin has branch at the same commit as the local branch.
+func isPushed(branch string) bool {
+	local, err := utils.RunCmd("git", "rev-parse", "--verify", "refs/heads/"+branch)
+	if err != nil {
+		return false
+	}
+	remote, err := utils.RunCmd("git", "ls-remote", "origin", "refs/heads/"+branch)
+	if err != nil {
+		return false
 	}
+	fields := strings.Fields(remote)
+	return len(fields) > 0 && fields[0] == local
+}
 
-	if foundTask == nil {
-		fmt.Printf("No task note found with GitHub issue #%s.\n", issueNumber)
+// pushBranch pushes branch to origin, setting it as upstream; a dry run prints the
+// command instead.
+func pushBranch(out io.Writer, branch string, dryRun bool) error {
+	args := []string{"push", "--set-upstream", "origin", branch}
+	if dryRun {
+		fmt.Fprintln(out, "$", utils.ShellQuote(append([]string{"git"}, args...)))
 		return nil
 	}
-
-	foundTask.Status = config.StatusDone
-	foundTask.DoneDate = time.Now().Format("2006-01-02")
-
-	_, descriptionPart, err := ReadTaskFile(cfg, projectName, foundPath)
+	output, err := utils.RunCmd("git", args...)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s for update: %w", foundPath, err)
+		return fmt.Errorf("failed to push branch: %w", err)
 	}
-
-	if err := WriteTaskFile(cfg, projectName, foundPath, foundTask, descriptionPart); err != nil {
-		return fmt.Errorf("failed to write updated file %s: %w", foundPath, err)
+	if output != "" {
+		fmt.Fprintln(out, output)
 	}
-
 	return nil
 }
 
+// markNoteDone marks the task of a note done; a dry run prints the diff of the note
+// instead.
+func markNoteDone(out io.Writer, cfg config.Config, projectName, path string, dryRun bool) error {
+	done := func(t *config.Task) error {
+		t.Status = config.StatusDone
+		t.DoneDate = time.Now().Format(config.DateLayout)
+		return nil
+	}
+	if dryRun {
+		before, after, err := previewTask(cfg, projectName, path, done)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, utils.LineDiff(path, string(before), string(after)))
+		return nil
+	}
+	if _, err := updateTask(cfg, projectName, path, done); err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"tasky/config"
+	"tasky/forge"
+	"tasky/utils"
+)
+
+// offline runs a Service without a repository, a forge or a vault: git runs through a
+// Replay of script, the forge is a Fake and notes are kept in memory.
+type offline struct {
+	svc    *Service
+	cfg    config.Config
+	forge  *forge.Fake
+	replay *utils.Replay
+}
+
+func newOffline(t *testing.T) *offline {
+	t.Helper()
+	// The finish progress is kept in the user cache directory.
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	o := &offline{forge: forge.NewFake()}
+	o.cfg.General.Project = "proj"
+	o.svc = NewService(o.cfg)
+	utils.SetStoreBackend(utils.NewMemoryBackend())
+	forge.SetForge(o.forge)
+	t.Cleanup(func() {
+		utils.SetStoreBackend(nil)
+		utils.SetRunner(nil)
+		forge.SetForge(nil)
+	})
+	return o
+}
+
+// play makes git commands answer from script until the next call, checking that they
+// were all run.
+func (o *offline) play(t *testing.T, script ...utils.Expect) {
+	t.Helper()
+	o.verify(t)
+	o.replay = utils.NewReplay(script...)
+	utils.SetRunner(o.replay)
+}
+
+func (o *offline) verify(t *testing.T) {
+	t.Helper()
+	if o.replay == nil {
+		return
+	}
+	if err := o.replay.Verify(); err != nil {
+		t.Fatalf("git commands: %v\nran: %q", err, o.replay.Calls)
+	}
+}
+
+func (o *offline) assertStatus(t *testing.T, ref, want string) {
+	t.Helper()
+	utils.SetRunner(utils.NewReplay()) // reading notes runs no command
+	e, err := o.svc.Find("proj", ref)
+	utils.SetRunner(o.replay)
+	if err != nil {
+		t.Fatalf("Find(%s): %v", ref, err)
+	}
+	if e.Status != want {
+		t.Errorf("status of %s: got %q, want %q", ref, e.Status, want)
+	}
+}
+
+func git(command, output string) utils.Expect {
+	return utils.Expect{Command: command, Output: output}
+}
+
+const toplevel = "/src/proj"
+
+// finishScript is what Finish runs on branch before reaching its steps, with the branch
+// pushed when pushed is set.
+func finishScript(branch string, pushed bool) []utils.Expect {
+	remote := ""
+	if pushed {
+		remote = "abc123\trefs/heads/" + branch
+	}
+	return []utils.Expect{
+		git("git rev-parse --abbrev-ref HEAD", branch),
+		git("git rev-parse --show-toplevel", toplevel),
+		git("git rev-parse --verify refs/heads/"+branch, "abc123"),
+		git("git ls-remote origin refs/heads/"+branch, remote),
+	}
+}
+
+// checkoutScript is what StartDevelopment runs to check out a new branch.
+func checkoutScript(branch string) []utils.Expect {
+	return []utils.Expect{
+		git("git rev-parse --is-inside-work-tree", "true"),
+		{Command: "git rev-parse --verify --quiet refs/heads/" + branch, Err: errors.New("exit status 1")},
+		git("git checkout -b "+branch, ""),
+	}
+}
+
+func TestNewStartFinishOffline(t *testing.T) {
+	o := newOffline(t)
+
+	o.play(t)
+	created, err := o.svc.Create("Fix login", CreateOptions{CreateIssue: true, CreateProject: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Issue == nil || created.Task.Issue != created.Issue.Number {
+		t.Fatalf("Create opened no issue: %+v", created)
+	}
+
+	o.play(t, git("git rev-parse --is-inside-work-tree", "true"))
+	branch, err := o.svc.StartDevelopment(&created.Task, created.Task.Issue, "")
+	if err != nil {
+		t.Fatalf("StartDevelopment: %v", err)
+	}
+	if want := utils.BranchName(o.cfg, "", created.Task.Issue, created.ID, "Fix login"); branch != want {
+		t.Errorf("StartDevelopment checked out %s, want %s", branch, want)
+	}
+	if _, err := o.svc.MarkInProgress("proj", created.ID); err != nil {
+		t.Fatalf("MarkInProgress: %v", err)
+	}
+
+	o.play(t, append(finishScript(branch, false),
+		git("git push --set-upstream origin "+branch, ""),
+	)...)
+	if err := o.svc.Finish(FinishOptions{}); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	o.verify(t)
+
+	wantCalls := []string{
+		"CreateIssue Fix login",
+		"ViewIssue 1",
+		"DevelopIssue 1 " + branch,
+		"ViewIssue 1",
+		"FindPullRequest " + branch,
+		"CreatePullRequest Fix login draft=false",
+		"MergePullRequest 2 squash deleteBranch=true",
+	}
+	if !reflect.DeepEqual(o.forge.Calls, wantCalls) {
+		t.Errorf("forge calls:\ngot  %q\nwant %q", o.forge.Calls, wantCalls)
+	}
+	o.assertStatus(t, created.ID, config.StatusDone)
+}
+
+func TestFinishResumesAfterStopping(t *testing.T) {
+	o := newOffline(t)
+	o.play(t)
+	created, err := o.svc.Create("Add search", CreateOptions{CreateProject: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	branch := utils.BranchName(o.cfg, "", 0, created.ID, "Add search")
+	o.play(t, checkoutScript(branch)...)
+	if _, err := o.svc.StartDevelopment(&created.Task, 0, ""); err != nil {
+		t.Fatalf("StartDevelopment: %v", err)
+	}
+	o.forge.Branch = branch
+
+	// Stop before merging: the pull request is recorded.
+	o.play(t, finishScript(branch, true)...)
+	confirm := func(step string) bool { return step == "Create Fake pull request 'Add search'" }
+	if err := o.svc.Finish(FinishOptions{Confirm: confirm}); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Finish: got %v, want ErrStopped", err)
+	}
+	o.assertStatus(t, created.ID, config.StatusTodo)
+
+	// From a branch without task, the stopped branch is only resumed when agreed to.
+	o.play(t, git("git rev-parse --abbrev-ref HEAD", "main"), git("git rev-parse --show-toplevel", toplevel))
+	if err := o.svc.Finish(FinishOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Finish from main without Resume: got %v, want ErrNotFound", err)
+	}
+
+	var asked string
+	resume := func(b string) bool { asked = b; return true }
+	o.play(t, append([]utils.Expect{
+		git("git rev-parse --abbrev-ref HEAD", "main"),
+		git("git rev-parse --show-toplevel", toplevel),
+	}, finishScript(branch, true)[2:]...)...)
+	if err := o.svc.Finish(FinishOptions{Resume: resume}); err != nil {
+		t.Fatalf("Finish resuming %s: %v", branch, err)
+	}
+	o.verify(t)
+	if asked != branch {
+		t.Errorf("Resume asked for %q, want %q", asked, branch)
+	}
+	if o.forge.Calls[len(o.forge.Calls)-1] != "MergePullRequest 1 squash deleteBranch=true" {
+		t.Errorf("forge calls: %q", o.forge.Calls)
+	}
+	o.assertStatus(t, created.ID, config.StatusDone)
+
+	// Nothing is left to resume.
+	o.play(t, git("git rev-parse --abbrev-ref HEAD", "main"), git("git rev-parse --show-toplevel", toplevel))
+	if err := o.svc.Finish(FinishOptions{Resume: resume}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Finish after finishing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFinishFailedPushIsNotResumed(t *testing.T) {
+	o := newOffline(t)
+	o.play(t)
+	created, err := o.svc.Create("Add search", CreateOptions{CreateProject: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	branch := utils.BranchName(o.cfg, "", 0, created.ID, "Add search")
+	o.play(t, checkoutScript(branch)...)
+	if _, err := o.svc.StartDevelopment(&created.Task, 0, ""); err != nil {
+		t.Fatalf("StartDevelopment: %v", err)
+	}
+
+	pushFailed := errors.New("rejected")
+	o.play(t, append(finishScript(branch, false), utils.Expect{Command: "git push --set-upstream origin " + branch, Err: pushFailed})...)
+	if err := o.svc.Finish(FinishOptions{}); !errors.Is(err, pushFailed) {
+		t.Fatalf("Finish: got %v, want the push error", err)
+	}
+
+	resume := func(b string) bool {
+		t.Errorf("Resume asked for %s, for which nothing was done", b)
+		return true
+	}
+	o.play(t, git("git rev-parse --abbrev-ref HEAD", "main"), git("git rev-parse --show-toplevel", toplevel))
+	if err := o.svc.Finish(FinishOptions{Resume: resume}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Finish from main: got %v, want ErrNotFound", err)
+	}
+	o.verify(t)
+}
@@ -0,0 +1,374 @@
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tasky/config"
+)
+
+// noteParts is a note split around its frontmatter. Concatenating the parts gives back the
+// note byte for byte.
+type noteParts struct {
+	open        string // opening delimiter line
+	frontmatter string // YAML between the delimiters
+	close       string // closing delimiter line
+	body        string // everything after the closing delimiter
+	newline     string // line ending of the note, "\n" or "\r\n"
+}
+
+// splitNote finds the frontmatter at the start of a note: a "---" line, the YAML, then a
+// "---" (or "...") line.
+func splitNote(content []byte) (noteParts, bool) {
+	text := string(content)
+	parts := noteParts{newline: "\n"}
+	switch {
+	case strings.HasPrefix(text, "---\r\n"):
+		parts.newline = "\r\n"
+	case strings.HasPrefix(text, "---\n"):
+	default:
+		return noteParts{}, false
+	}
+	parts.open = "---" + parts.newline
+	rest := text[len(parts.open):]
+	for offset := 0; ; {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end+1]
+		}
+		if delimiter := strings.TrimRight(line, "\r\n"); delimiter == "---" || delimiter == "..." {
+			parts.frontmatter = rest[:offset]
+			parts.close = line
+			parts.body = rest[offset+len(line):]
+			return parts, true
+		}
+		if end < 0 {
+			return noteParts{}, false
+		}
+		offset += end + 1
+	}
+}
+
+// frontmatterField is a field of config.Frontmatter, whose frontmatter key tasky owns.
+// Its name is the default key, which the note format may rename.
+type frontmatterField struct {
+	name      string
+	omitEmpty bool
+	index     int
+}
+
+// ownedFields lists the fields of config.Frontmatter, in declaration order.
+var ownedFields = func() []frontmatterField {
+	var fields []frontmatterField
+	t := reflect.TypeOf(config.Frontmatter{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")
+		field := frontmatterField{name: tag[0], index: i}
+		for _, option := range tag[1:] {
+			field.omitEmpty = field.omitEmpty || option == "omitempty"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}()
+
+// decodeFrontmatter reads the fields of a task from frontmatter in the given format.
+func decodeFrontmatter(text string, format config.NoteFormat) (*config.Task, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, err
+	}
+	var fm config.Frontmatter
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return &config.Task{Frontmatter: fm}, nil
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("frontmatter is not a mapping")
+	}
+	values := reflect.ValueOf(&fm).Elem()
+	for _, field := range ownedFields {
+		if i := findKey(mapping, format.Key(field.name)); i >= 0 {
+			if err := decodeValue(mapping.Content[i+1], values.Field(field.index)); err != nil {
+				return nil, fmt.Errorf("%s: %w", format.Key(field.name), err)
+			}
+		}
+	}
+	return &config.Task{Frontmatter: fromNote(fm, format)}, nil
+}
+
+// decodeValue decodes a frontmatter value into dst. Numbers written as strings, such as
+// issue: "12", are accepted, as other tools quote them.
+func decodeValue(node *yaml.Node, dst reflect.Value) error {
+	if dst.Kind() == reflect.Int && node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		value := strings.TrimSpace(node.Value)
+		if value == "" {
+			dst.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", node.Value)
+		}
+		dst.SetInt(int64(n))
+		return nil
+	}
+	return node.Decode(dst.Addr().Interface())
+}
+
+// renderFrontmatter renders the frontmatter of a new note.
+func renderFrontmatter(task *config.Task, format config.NoteFormat) (string, error) {
+	var lines []string
+	values := reflect.ValueOf(toNote(task.Frontmatter, format))
+	for _, field := range ownedFields {
+		value := values.Field(field.index)
+		if field.omitEmpty && isEmptyValue(value) {
+			continue
+		}
+		rendered, err := renderField(format.Key(field.name), value.Interface(), 0)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, rendered...)
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// updateFrontmatter rewrites only the lines of the keys tasky owns whose value differs
+// from the task, so unknown keys, comments, key order and formatting are kept as they
+// are. Changed keys are edited in place, keys that gained a value are appended and
+// emptied omitempty keys are removed.
+func updateFrontmatter(text string, task *config.Task, format config.NoteFormat, newline string) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return "", err
+	}
+	var mapping *yaml.Node
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		mapping = doc.Content[0]
+		if mapping.Kind != yaml.MappingNode {
+			return "", fmt.Errorf("frontmatter is not a mapping")
+		}
+		if mapping.Style&yaml.FlowStyle != 0 {
+			return "", fmt.Errorf("flow style frontmatter ({...}) cannot be updated")
+		}
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	// Every edit replaces lines[start:end]; they are applied bottom up so that earlier
+	// line numbers stay valid.
+	type edit struct {
+		start, end int
+		lines      []string
+	}
+	var edits []edit
+	var appended []string
+
+	values := reflect.ValueOf(toNote(task.Frontmatter, format))
+	for _, field := range ownedFields {
+		key := format.Key(field.name)
+		value := values.Field(field.index)
+		empty := isEmptyValue(value)
+
+		keyIndex := -1
+		if mapping != nil {
+			keyIndex = findKey(mapping, key)
+		}
+		if keyIndex < 0 {
+			if empty {
+				continue
+			}
+			rendered, err := renderField(key, value.Interface(), 0)
+			if err != nil {
+				return "", err
+			}
+			appended = append(appended, rendered...)
+			continue
+		}
+
+		keyNode, valueNode := mapping.Content[keyIndex], mapping.Content[keyIndex+1]
+		current := reflect.New(value.Type()).Elem()
+		if err := decodeValue(valueNode, current); err == nil && sameValue(current, value) {
+			continue
+		}
+
+		start := keyNode.Line - 1
+		end := len(lines)
+		if keyIndex+2 < len(mapping.Content) {
+			end = mapping.Content[keyIndex+2].Line - 1
+		}
+		// Blank lines and comments before the next key belong to it.
+		for end > start+1 && isBlankOrComment(lines[end-1]) {
+			end--
+		}
+
+		if empty && field.omitEmpty {
+			edits = append(edits, edit{start, end, nil})
+			continue
+		}
+		if line, ok := replaceScalar(lines[start], keyNode, valueNode, value.Interface()); ok && end == start+1 {
+			edits = append(edits, edit{start, end, []string{line}})
+			continue
+		}
+		rendered, err := renderField(key, value.Interface(), keyNode.Column-1)
+		if err != nil {
+			return "", err
+		}
+		edits = append(edits, edit{start, end, rendered})
+	}
+
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		updated := append([]string{}, lines[:e.start]...)
+		updated = append(updated, e.lines...)
+		lines = append(updated, lines[e.end:]...)
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		lines[len(lines)-1] += newline
+	}
+	lines = append(lines, appended...)
+
+	result := strings.Join(lines, "")
+	if newline != "\n" {
+		// Lines rendered by tasky end with "\n" only.
+		result = strings.ReplaceAll(strings.ReplaceAll(result, "\r\n", "\n"), "\n", newline)
+	}
+	return result, nil
+}
+
+// findKey returns the index of key in the content of a mapping node, or -1.
+func findKey(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// toNote converts a task's fields to the values written in notes: configured status
+// values and date formats.
+func toNote(fm config.Frontmatter, format config.NoteFormat) config.Frontmatter {
+	fm.Status = format.StatusValue(fm.Status)
+	for _, date := range []*string{&fm.CreatedDate, &fm.StartDate, &fm.DoneDate} {
+		*date = convertDate(*date, config.DateTimeLayout, format.DateTimeFormat, config.DateLayout, format.DateFormat)
+	}
+	return fm
+}
+
+// fromNote converts the values read from a note back to the statuses and date layouts
+// tasks use. Values in neither the configured nor the default format are kept as is.
+func fromNote(fm config.Frontmatter, format config.NoteFormat) config.Frontmatter {
+	for _, status := range []string{config.StatusTodo, config.StatusInProgress, config.StatusDone} {
+		if strings.EqualFold(fm.Status, format.StatusValue(status)) {
+			fm.Status = status
+			break
+		}
+	}
+	for _, date := range []*string{&fm.CreatedDate, &fm.StartDate, &fm.DoneDate} {
+		*date = convertDate(*date, format.DateTimeFormat, config.DateTimeLayout, format.DateFormat, config.DateLayout)
+	}
+	return fm
+}
+
+// convertDate reformats a date and time from fromDateTime to toDateTime, or a date from
+// fromDate to toDate. Empty layouts are skipped, and a value matching neither is kept.
+func convertDate(value, fromDateTime, toDateTime, fromDate, toDate string) string {
+	if value == "" {
+		return value
+	}
+	if fromDateTime != "" && toDateTime != "" {
+		if t, err := time.ParseInLocation(fromDateTime, value, time.Local); err == nil {
+			return t.Format(toDateTime)
+		}
+	}
+	if fromDate != "" && toDate != "" {
+		if t, err := time.ParseInLocation(fromDate, value, time.Local); err == nil {
+			return t.Format(toDate)
+		}
+	}
+	return value
+}
+
+// replaceScalar replaces the value of a "key: value" line with a new scalar value,
+// keeping the quoting style and a trailing comment. It reports false when the value
+// does not fit on the key's line.
+func replaceScalar(line string, keyNode, valueNode *yaml.Node, value interface{}) (string, bool) {
+	if valueNode.Kind != yaml.ScalarNode || valueNode.Line != keyNode.Line || valueNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return "", false
+	}
+	var node yaml.Node
+	if err := node.Encode(value); err != nil || node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	if valueNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 && node.Tag == "!!str" {
+		node.Style = valueNode.Style
+	}
+	encoded, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", false
+	}
+	rendered := strings.TrimSuffix(string(encoded), "\n")
+	// Columns count characters, not bytes.
+	prefix := []rune(line)
+	if strings.Contains(rendered, "\n") || valueNode.Column-1 > len(prefix) {
+		return "", false
+	}
+	updated := string(prefix[:valueNode.Column-1]) + rendered
+	if valueNode.LineComment != "" {
+		updated += " " + valueNode.LineComment
+	}
+	return updated + "\n", true
+}
+
+// renderField renders "key: value" as YAML lines indented by indent spaces.
+func renderField(key string, value interface{}, indent int) ([]string, error) {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: key}, &node}}
+	if err := enc.Encode(mapping); err != nil {
+		return nil, err
+	}
+	enc.Close()
+	lines := strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.Repeat(" ", indent) + strings.TrimSuffix(lines[i], "\n") + "\n"
+	}
+	return lines, nil
+}
+
+// isEmptyValue reports whether a frontmatter value counts as unset.
+func isEmptyValue(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// sameValue compares a value read from the frontmatter with the task's.
+func sameValue(a, b reflect.Value) bool {
+	if isEmptyValue(a) && isEmptyValue(b) {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
@@ -0,0 +1,314 @@
+package task
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"tasky/config"
+)
+
+// renamed is a note format whose keys, statuses and dates differ from the defaults.
+var renamed = config.NoteFormat{
+	Keys:       map[string]string{"status": "state", "created_date": "created", "done_date": "completed"},
+	Status:     config.StatusValues{InProgress: "doing"},
+	DateFormat: "02/01/2006",
+}
+
+func TestRenderTaskContentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		format config.NoteFormat
+		note   string
+		change func(*config.Task)
+		want   string // the note after the change; empty means unchanged
+	}{
+		{
+			name: "unknown keys and comments",
+			note: "---\n" +
+				"# managed by tasky\n" +
+				"id: abc123\n" +
+				"title: Write docs\n" +
+				"aliases: [docs, documentation]\n" +
+				"status: todo # not started\n" +
+				"created_date: 2024-01-02 10:00\n" +
+				"pomodoro_count: 0\n" +
+				"custom:\n" +
+				"  nested: true\n" +
+				"---\n\nBody text\n",
+		},
+		{
+			name: "status keeps its trailing comment",
+			note: "---\n" +
+				"id: abc123\n" +
+				"title: Write docs\n" +
+				"status: todo # not started\n" +
+				"created_date: 2024-01-02 10:00\n" +
+				"pomodoro_count: 0\n" +
+				"custom:\n" +
+				"  nested: true\n" +
+				"---\n\nBody text\n",
+			change: func(task *config.Task) {
+				task.Status = config.StatusInProgress
+				task.StartDate = "2024-01-03 09:00"
+			},
+			want: "---\n" +
+				"id: abc123\n" +
+				"title: Write docs\n" +
+				"status: in progress # not started\n" +
+				"created_date: 2024-01-02 10:00\n" +
+				"pomodoro_count: 0\n" +
+				"custom:\n" +
+				"  nested: true\n" +
+				"start_date: 2024-01-03 09:00\n" +
+				"---\n\nBody text\n",
+		},
+		{
+			name: "CRLF unchanged",
+			note: "---\r\nid: abc123\r\ntitle: T\r\nstatus: todo\r\ncreated_date: 2024-01-02\r\npomodoro_count: 0\r\n---\r\n\r\nBody\r\n",
+		},
+		{
+			name: "CRLF kept on edit",
+			note: "---\r\nid: abc123\r\ntitle: T\r\nstatus: todo\r\ncreated_date: 2024-01-02\r\npomodoro_count: 0\r\n---\r\n\r\nBody\r\n",
+			change: func(task *config.Task) {
+				task.PomodoroCount = 2
+				task.Tags = config.Tags{"a"}
+			},
+			want: "---\r\nid: abc123\r\ntitle: T\r\nstatus: todo\r\ncreated_date: 2024-01-02\r\npomodoro_count: 2\r\ntags:\r\n  - a\r\n---\r\n\r\nBody\r\n",
+		},
+		{
+			name: "quoted scalars unchanged",
+			note: "---\n" +
+				"id: 'abc123'\n" +
+				"title: \"Fix: the \\\"parser\\\"\"\n" +
+				"status: 'todo'\n" +
+				"created_date: \"2024-01-02\"\n" +
+				"pomodoro_count: 0\n" +
+				"issue: \"12\"\n" +
+				"---\n\nBody\n",
+		},
+		{
+			name: "quoted scalars keep their style",
+			note: "---\n" +
+				"id: 'abc123'\n" +
+				"title: \"Fix: the \\\"parser\\\"\"\n" +
+				"status: 'todo'\n" +
+				"created_date: \"2024-01-02\"\n" +
+				"pomodoro_count: 0\n" +
+				"issue: \"12\"\n" +
+				"---\n\nBody\n",
+			change: func(task *config.Task) {
+				task.Status = config.StatusDone
+				task.Title = "Fix the parser"
+			},
+			want: "---\n" +
+				"id: 'abc123'\n" +
+				"title: \"Fix the parser\"\n" +
+				"status: 'done'\n" +
+				"created_date: \"2024-01-02\"\n" +
+				"pomodoro_count: 0\n" +
+				"issue: \"12\"\n" +
+				"---\n\nBody\n",
+		},
+		{
+			name: "block list unchanged",
+			note: "---\n" +
+				"id: abc123\n" +
+				"title: T\n" +
+				"tags:\n" +
+				"    - work   # indented by four\n" +
+				"    - home\n" +
+				"# the issue\n" +
+				"issue: 3\n" +
+				"status: todo\n" +
+				"created_date: 2024-01-02\n" +
+				"pomodoro_count: 0\n" +
+				"---\n\nBody\n",
+		},
+		{
+			name: "block list replaced",
+			note: "---\n" +
+				"id: abc123\n" +
+				"title: T\n" +
+				"tags:\n" +
+				"    - work   # indented by four\n" +
+				"    - home\n" +
+				"# the issue\n" +
+				"issue: 3\n" +
+				"status: todo\n" +
+				"created_date: 2024-01-02\n" +
+				"pomodoro_count: 0\n" +
+				"---\n\nBody\n",
+			change: func(task *config.Task) {
+				task.Tags = append(task.Tags, "urgent")
+			},
+			want: "---\n" +
+				"id: abc123\n" +
+				"title: T\n" +
+				"tags:\n" +
+				"  - work\n" +
+				"  - home\n" +
+				"  - urgent\n" +
+				"# the issue\n" +
+				"issue: 3\n" +
+				"status: todo\n" +
+				"created_date: 2024-01-02\n" +
+				"pomodoro_count: 0\n" +
+				"---\n\nBody\n",
+		},
+		{
+			name: "emptied block list removed",
+			note: "---\n" +
+				"id: abc123\n" +
+				"title: T\n" +
+				"tags:\n" +
+				"  - work\n" +
+				"# the issue\n" +
+				"issue: 3\n" +
+				"---\n\nBody\n",
+			change: func(task *config.Task) {
+				task.Tags = nil
+			},
+			want: "---\n" +
+				"id: abc123\n" +
+				"title: T\n" +
+				"# the issue\n" +
+				"issue: 3\n" +
+				"---\n\nBody\n",
+		},
+		{
+			name:   "renamed keys unchanged",
+			format: renamed,
+			note:   "---\nid: abc123\ntitle: T\nstate: doing\ncreated: 02/01/2024\npomodoro_count: 1\n---\n\nBody\n",
+		},
+		{
+			name:   "renamed keys edited",
+			format: renamed,
+			note:   "---\nid: abc123\ntitle: T\nstate: doing\ncreated: 02/01/2024\npomodoro_count: 1\n---\n\nBody\n",
+			change: func(task *config.Task) {
+				task.Status = config.StatusDone
+				task.DoneDate = "2024-01-05"
+			},
+			want: "---\nid: abc123\ntitle: T\nstate: done\ncreated: 02/01/2024\npomodoro_count: 1\ncompleted: 05/01/2024\n---\n\nBody\n",
+		},
+		{
+			name: "body edited",
+			note: "---\nid: abc123\ntitle: T\nstatus: todo\ncreated_date: 2024-01-02\npomodoro_count: 0\n---\n\nOld body\n",
+			change: func(task *config.Task) {
+				task.Title = "New title"
+			},
+			want: "---\nid: abc123\ntitle: New title\nstatus: todo\ncreated_date: 2024-01-02\npomodoro_count: 0\n---\n\nOld body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, body, err := parseTaskContent([]byte(tt.note), tt.format, "note.md")
+			if err != nil {
+				t.Fatalf("parseTaskContent: %v", err)
+			}
+			want := tt.note
+			if tt.change != nil {
+				tt.change(task)
+				want = tt.want
+			}
+			got, err := renderTaskContent([]byte(tt.note), tt.format, task, body, "note.md")
+			if err != nil {
+				t.Fatalf("renderTaskContent: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("got\n%q\nwant\n%q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseTaskContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  config.NoteFormat
+		note    string
+		want    config.Frontmatter
+		wantErr string
+	}{
+		{
+			name: "plain",
+			note: "---\nid: abc123\ntitle: T\nstatus: in progress\ncreated_date: 2024-01-02 10:00\npomodoro_count: 2\nissue: 12\ntags: [a, b]\n---\nBody",
+			want: config.Frontmatter{ID: "abc123", Title: "T", Status: config.StatusInProgress, CreatedDate: "2024-01-02 10:00", PomodoroCount: 2, Issue: 12, Tags: config.Tags{"a", "b"}},
+		},
+		{
+			name: "quoted integers",
+			note: "---\ntitle: T\nissue: \"12\"\npomodoro_count: '3'\nduration: \"\"\n---\n",
+			want: config.Frontmatter{Title: "T", Issue: 12, PomodoroCount: 3},
+		},
+		{
+			name:    "quoted non-integer",
+			note:    "---\ntitle: T\nissue: \"twelve\"\n---\n",
+			wantErr: "issue: 'twelve' is not a number",
+		},
+		{
+			name: "tags as a string",
+			note: "---\ntitle: T\ntags: work, home\n---\n",
+			want: config.Frontmatter{Title: "T", Tags: config.Tags{"work", "home"}},
+		},
+		{
+			name:   "renamed keys, statuses and dates",
+			format: renamed,
+			note:   "---\ntitle: T\nstate: Doing\ncreated: 02/01/2024\ncompleted: 05/01/2024\nstatus: ignored\n---\n",
+			want:   config.Frontmatter{Title: "T", Status: config.StatusInProgress, CreatedDate: "2024-01-02", DoneDate: "2024-01-05"},
+		},
+		{
+			name: "CRLF and end marker",
+			note: "---\r\ntitle: T\r\nstatus: done\r\n...\r\nBody\r\n",
+			want: config.Frontmatter{Title: "T", Status: config.StatusDone},
+		},
+		{
+			name:    "no frontmatter",
+			note:    "title: T\n",
+			wantErr: "no YAML frontmatter found in note.md",
+		},
+		{
+			name:    "unterminated frontmatter",
+			note:    "---\ntitle: T\n",
+			wantErr: "no YAML frontmatter found in note.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, _, err := parseTaskContent([]byte(tt.note), tt.format, "note.md")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTaskContent: %v", err)
+			}
+			if !reflect.DeepEqual(task.Frontmatter, tt.want) {
+				t.Errorf("got %+v, want %+v", task.Frontmatter, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTaskContentNewNote(t *testing.T) {
+	for _, format := range []config.NoteFormat{{}, renamed} {
+		task := &config.Task{Frontmatter: config.Frontmatter{
+			ID: "abc123", Title: "Quote \"me\": please", Status: config.StatusInProgress,
+			CreatedDate: "2024-01-02 10:00", StartDate: "2024-01-02 11:00", Issue: 7, Tags: config.Tags{"a"},
+		}}
+		content, err := renderTaskContent(nil, format, task, "Body", "note.md")
+		if err != nil {
+			t.Fatalf("renderTaskContent: %v", err)
+		}
+		parsed, body, err := parseTaskContent(content, format, "note.md")
+		if err != nil {
+			t.Fatalf("parseTaskContent: %v\n%s", err, content)
+		}
+		if !reflect.DeepEqual(parsed.Frontmatter, task.Frontmatter) || body != "Body" {
+			t.Errorf("round trip of\n%s\ngave %+v and %q", content, parsed.Frontmatter, body)
+		}
+	}
+}
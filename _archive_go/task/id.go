@@ -0,0 +1,123 @@
+package task
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// idAlphabet avoids characters that are easy to confuse when typed by hand (i, l, o, u).
+const idAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// idLength is the number of characters in a generated task ID.
+const idLength = 6
+
+// minIDPrefix is the shortest ID prefix accepted when resolving a task reference.
+const minIDPrefix = 3
+
+// taskFile pairs a parsed task with its name in the project's task store.
+type taskFile struct {
+	Path string
+	Task *config.Task
+}
+
+// NewID returns a short random task ID that is not already present in existing.
+func NewID(existing map[string]bool) (string, error) {
+	buf := make([]byte, idLength)
+	for attempt := 0; attempt < 100; attempt++ {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("could not generate task ID: %w", err)
+		}
+		id := make([]byte, idLength)
+		for i, b := range buf {
+			id[i] = idAlphabet[int(b)%len(idAlphabet)]
+		}
+		if !existing[string(id)] {
+			return string(id), nil
+		}
+	}
+	return "", fmt.Errorf("could not generate a unique task ID")
+}
+
+// ListProjects returns the projects known to the configured storage backend.
+func ListProjects(cfg config.Config) ([]string, error) {
+	backend, err := utils.NewStoreBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Projects()
+}
+
+// FindTask resolves a task reference within a project. The reference may be a task ID,
+// a unique ID prefix, an issue number (optionally prefixed with '#') or a task title.
+// It returns the task and its name in the project's task store.
+func FindTask(cfg config.Config, projectName string, ref string) (*config.Task, string, error) {
+	store, err := utils.OpenTaskStore(cfg, projectName)
+	if err != nil {
+		return nil, "", fmt.Errorf("error opening task store: %w", err)
+	}
+	files, err := scanStore(store, cfg.NoteFormat, false)
+	if err != nil {
+		return nil, "", err
+	}
+	found, err := resolveRef(files, ref)
+	if err == nil && indexIsStale(store, cfg.NoteFormat, found) {
+		// The index disagrees with the note on disk: rebuild it and resolve again.
+		if files, err = scanStore(store, cfg.NoteFormat, true); err != nil {
+			return nil, "", err
+		}
+		found, err = resolveRef(files, ref)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return found.Task, found.Path, nil
+}
+
+// resolveRef picks the single task matching ref, trying the most specific kind of
+// reference first.
+func resolveRef(files []taskFile, ref string) (*taskFile, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("empty task reference")
+	}
+	lowerRef := strings.ToLower(ref)
+
+	matchers := []func(t *config.Task) bool{
+		func(t *config.Task) bool { return t.ID == lowerRef },
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err == nil && n > 0 {
+		matchers = append(matchers, func(t *config.Task) bool { return t.Issue == n })
+	}
+	if len(ref) >= minIDPrefix {
+		matchers = append(matchers, func(t *config.Task) bool { return strings.HasPrefix(t.ID, lowerRef) })
+	}
+	matchers = append(matchers, func(t *config.Task) bool { return strings.EqualFold(t.Title, ref) })
+
+	for _, match := range matchers {
+		var matches []*taskFile
+		for i := range files {
+			if match(files[i].Task) {
+				matches = append(matches, &files[i])
+			}
+		}
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			var candidates []string
+			for _, m := range matches {
+				candidates = append(candidates, fmt.Sprintf("%s (%s)", m.Task.ID, m.Task.Title))
+			}
+			return nil, fmt.Errorf("task reference '%s' is %w, candidates: %s", ref, ErrAmbiguous, strings.Join(candidates, ", "))
+		}
+	}
+
+	return nil, fmt.Errorf("task '%s' %w", ref, ErrNotFound)
+}
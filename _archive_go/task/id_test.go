@@ -0,0 +1,144 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+func TestNewID(t *testing.T) {
+	existing := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		id, err := NewID(existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != idLength || strings.Trim(id, idAlphabet) != "" {
+			t.Fatalf("NewID() = %q, want %d characters of %q", id, idLength, idAlphabet)
+		}
+		if existing[id] {
+			t.Fatalf("NewID() returned %q twice", id)
+		}
+		existing[id] = true
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	files := []taskFile{
+		{Path: "a.md", Task: &config.Task{Frontmatter: config.Frontmatter{ID: "abc123", Title: "Fix login"}}},
+		{Path: "b.md", Task: &config.Task{Frontmatter: config.Frontmatter{ID: "abc456", Title: "Add search", Issue: 12}}},
+		{Path: "c.md", Task: &config.Task{Frontmatter: config.Frontmatter{ID: "xyz789", Title: "abc"}}},
+		{Path: "d.md", Task: &config.Task{Frontmatter: config.Frontmatter{ID: "def000", Title: "Same title"}}},
+		{Path: "e.md", Task: &config.Task{Frontmatter: config.Frontmatter{ID: "def111", Title: "same title"}}},
+	}
+	tests := []struct {
+		ref     string
+		want    string // path of the task found
+		wantErr error
+	}{
+		{ref: "abc123", want: "a.md"},
+		{ref: "ABC123", want: "a.md"},
+		{ref: " xyz789 ", want: "c.md"},
+		{ref: "xyz", want: "c.md"},
+		{ref: "abc4", want: "b.md"},
+		{ref: "abc", wantErr: ErrAmbiguous}, // a prefix of two IDs wins over a title
+		{ref: "ab", wantErr: ErrNotFound},   // too short for a prefix
+		{ref: "#12", want: "b.md"},
+		{ref: "12", want: "b.md"},
+		{ref: "#13", wantErr: ErrNotFound},
+		{ref: "fix LOGIN", want: "a.md"},
+		{ref: "Same title", wantErr: ErrAmbiguous},
+		{ref: "nothing", wantErr: ErrNotFound},
+		{ref: "", wantErr: errors.New("empty")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			found, err := resolveRef(files, tt.ref)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("resolveRef: %v", err)
+			case tt.wantErr == nil && found.Path != tt.want:
+				t.Errorf("resolveRef found %s, want %s", found.Path, tt.want)
+			case tt.wantErr != nil && err == nil:
+				t.Errorf("resolveRef found %s, want an error", found.Path)
+			case tt.wantErr == ErrAmbiguous || tt.wantErr == ErrNotFound:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("resolveRef: got %v, want %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestScanStoreBackfillsIDs(t *testing.T) {
+	notes := map[string]string{
+		"no-id.md":    "---\ntitle: No ID yet\nstatus: todo\n---\nBody\n",
+		"a-first.md":  "---\nid: abc123\ntitle: First\nstatus: done\n---\n",
+		"b-copy.md":   "---\nid: abc123\ntitle: Copy of first\nstatus: todo\n---\n",
+		"daily.md":    "---\ntags: [daily]\n---\nNot a task.\n",
+		"untitled.md": "---\nstatus: todo\n---\n",
+		"other.md":    "---\ntitle: Meeting\nstatus: scheduled\n---\n",
+		"plain.md":    "# No frontmatter\n",
+	}
+	store := utils.NewMemoryStore()
+	for name, content := range notes {
+		if err := store.Put(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := scanStore(store, config.NoteFormat{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]string)
+	for _, f := range files {
+		if f.Task.ID == "" {
+			t.Errorf("%s has no ID", f.Path)
+		}
+		if other, ok := ids[f.Task.ID]; ok {
+			t.Errorf("%s and %s share the ID %s", other, f.Path, f.Task.ID)
+		}
+		ids[f.Task.ID] = f.Path
+	}
+	if len(files) != 3 {
+		t.Fatalf("scanned %d tasks, want 3: %+v", len(files), files)
+	}
+	if ids["abc123"] != "a-first.md" {
+		t.Errorf("abc123 is %q, want the note listed first to keep it", ids["abc123"])
+	}
+	for _, name := range []string{"daily.md", "untitled.md", "other.md", "plain.md"} {
+		content, _ := store.Get(name)
+		if string(content) != notes[name] {
+			t.Errorf("%s is not a task but was rewritten:\n%s", name, content)
+		}
+	}
+	content, _ := store.Get("no-id.md")
+	if !strings.Contains(string(content), "Body") {
+		t.Errorf("backfilling lost the body:\n%s", content)
+	}
+
+	// The IDs are written once: a second scan finds them and rewrites nothing.
+	before := make(map[string]string)
+	for name := range notes {
+		content, _ := store.Get(name)
+		before[name] = string(content)
+	}
+	again, err := scanStore(store, config.NoteFormat{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range again {
+		if ids[f.Task.ID] != f.Path {
+			t.Errorf("%s got a new ID %s", f.Path, f.Task.ID)
+		}
+	}
+	for name := range notes {
+		if content, _ := store.Get(name); string(content) != before[name] {
+			t.Errorf("the second scan rewrote %s", name)
+		}
+	}
+}
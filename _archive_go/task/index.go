@@ -0,0 +1,321 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// indexVersion is bumped whenever the cached entry format changes, forcing a full rescan.
+const indexVersion = 2
+
+// errIndexInconsistent reports an index whose content cannot be trusted.
+var errIndexInconsistent = errors.New("task index is inconsistent")
+
+// indexEntry caches the parsed frontmatter of one note, valid as long as the note's
+// modification time and size are unchanged.
+type indexEntry struct {
+	ModTime time.Time   `json:"mod_time"`
+	Size    int64       `json:"size"`
+	Invalid bool        `json:"invalid,omitempty"` // the note is not a task, see isTask
+	Task    config.Task `json:"task"`
+}
+
+// taskIndex is the persistent cache of a store, kept under the user cache directory
+// (e.g. ~/.cache/tasky) and keyed by note name.
+type taskIndex struct {
+	Version int                   `json:"version"`
+	Entries map[string]indexEntry `json:"entries"`
+
+	path  string
+	dirty bool
+}
+
+// indexPath returns where the index of the store identified by key is cached.
+func indexPath(key string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cacheDir, "tasky", "index-"+hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// loadIndex reads the cached index of a store. A missing, unreadable or outdated index
+// yields an empty one, which amounts to a full rescan. Notes are parsed according to the
+// note format, so each format has its own index.
+func loadIndex(store utils.IndexableStore, format config.NoteFormat, rebuild bool) *taskIndex {
+	idx := &taskIndex{Version: indexVersion, Entries: make(map[string]indexEntry)}
+	path, err := indexPath(fmt.Sprintf("%s\x00%v", store.IndexKey(), format))
+	if err != nil {
+		return idx
+	}
+	idx.path = path
+	if rebuild {
+		idx.dirty = true
+		return idx
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return idx
+	}
+	var cached taskIndex
+	if err := json.Unmarshal(content, &cached); err != nil || cached.Version != indexVersion || cached.Entries == nil {
+		idx.dirty = true
+		return idx
+	}
+	idx.Entries = cached.Entries
+	return idx
+}
+
+// save writes the index back to the cache directory if it changed. Failing to cache is
+// not fatal: the next run simply rescans.
+func (idx *taskIndex) save() {
+	if !idx.dirty || idx.path == "" {
+		return
+	}
+	content, err := json.Marshal(idx)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return
+	}
+	tmpPath := idx.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmpPath, idx.path); err != nil {
+		os.Remove(tmpPath)
+		return
+	}
+	idx.dirty = false
+}
+
+// loadProjectTasks reads every task note of a project, from the index when the store
+// supports it. Task notes without an ID get one assigned and written back, so IDs are
+// backfilled the first time tasky sees a note; other notes are skipped and left alone.
+func loadProjectTasks(cfg config.Config, projectName string) ([]taskFile, error) {
+	store, err := utils.OpenTaskStore(cfg, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening task store: %w", err)
+	}
+	return scanStore(store, cfg.NoteFormat, false)
+}
+
+// scanStore returns the tasks of a store. Indexable stores only have their new or
+// modified notes parsed; rebuild discards the cached index first. If the index turns out
+// to be inconsistent, the scan is retried once as a full rebuild.
+func scanStore(store utils.TaskStore, format config.NoteFormat, rebuild bool) ([]taskFile, error) {
+	indexable, ok := store.(utils.IndexableStore)
+	if !ok {
+		files, err := readAllNotes(store, format)
+		if err != nil {
+			return nil, err
+		}
+		dropDuplicateIDs(files)
+		return files, backfillIDs(store, format, files, nil)
+	}
+
+	idx := loadIndex(indexable, format, rebuild)
+	files, err := refreshIndex(indexable, format, idx, rebuild)
+	if errors.Is(err, errIndexInconsistent) && !rebuild {
+		idx = loadIndex(indexable, format, true)
+		files, err = refreshIndex(indexable, format, idx, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := backfillIDs(store, format, files, idx); err != nil {
+		return nil, err
+	}
+	idx.save()
+	return files, nil
+}
+
+// readAllNotes parses every note of a store, keeping the tasks.
+func readAllNotes(store utils.TaskStore, format config.NoteFormat) ([]taskFile, error) {
+	names, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("error searching for tasks: %w", err)
+	}
+
+	var files []taskFile
+	for _, name := range names {
+		content, err := store.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %s: %w", name, err)
+		}
+		t, _, err := parseTaskContent(content, format, name)
+		if err == nil && isTask(t) {
+			files = append(files, taskFile{Path: name, Task: t})
+		}
+	}
+	return files, nil
+}
+
+// refreshIndex brings idx up to date with the store, parsing only the notes whose
+// modification time or size changed, and returns the indexed tasks. Duplicate IDs make
+// the index inconsistent unless it was just rebuilt, in which case they are real (e.g. a
+// copied note) and the later copies get a new ID.
+func refreshIndex(store utils.IndexableStore, format config.NoteFormat, idx *taskIndex, rebuilt bool) ([]taskFile, error) {
+	infos, err := store.ListInfo()
+	if err != nil {
+		return nil, fmt.Errorf("error searching for tasks: %w", err)
+	}
+
+	seen := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		seen[info.Name] = true
+		entry, ok := idx.Entries[info.Name]
+		if ok && entry.ModTime.Equal(info.ModTime) && entry.Size == info.Size {
+			continue
+		}
+		content, err := store.Get(info.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed between listing and reading.
+			delete(idx.Entries, info.Name)
+			delete(seen, info.Name)
+			idx.dirty = true
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %s: %w", info.Name, err)
+		}
+		entry = indexEntry{ModTime: info.ModTime, Size: info.Size}
+		if t, _, err := parseTaskContent(content, format, info.Name); err == nil && isTask(t) {
+			entry.Task = *t
+		} else {
+			entry.Invalid = true
+		}
+		idx.Entries[info.Name] = entry
+		idx.dirty = true
+	}
+	for name := range idx.Entries {
+		if !seen[name] {
+			delete(idx.Entries, name)
+			idx.dirty = true
+		}
+	}
+
+	var files []taskFile
+	for _, info := range infos {
+		entry, ok := idx.Entries[info.Name]
+		if !ok || entry.Invalid {
+			continue
+		}
+		t := entry.Task
+		files = append(files, taskFile{Path: info.Name, Task: &t})
+	}
+	if dropDuplicateIDs(files) && !rebuilt {
+		return nil, errIndexInconsistent
+	}
+	return files, nil
+}
+
+// isTask reports whether a parsed note is a tasky task rather than another note of the
+// vault that happens to have a frontmatter: it has a title and a known status.
+func isTask(t *config.Task) bool {
+	_, ok := parseStatus(t.Status)
+	return t.Title != "" && ok
+}
+
+// dropDuplicateIDs clears the ID of every task whose ID was already seen earlier in
+// files, so that backfillIDs assigns it a fresh one. It reports whether any was found.
+func dropDuplicateIDs(files []taskFile) bool {
+	ids := make(map[string]bool)
+	found := false
+	for _, f := range files {
+		if f.Task.ID == "" {
+			continue
+		}
+		if ids[f.Task.ID] {
+			f.Task.ID = ""
+			found = true
+			continue
+		}
+		ids[f.Task.ID] = true
+	}
+	return found
+}
+
+// backfillIDs assigns an ID to every task that lacks one and writes the note back, under
+// the store's lock. Rewritten notes are dropped from idx so that the next scan picks up
+// their new content.
+func backfillIDs(store utils.TaskStore, format config.NoteFormat, files []taskFile, idx *taskIndex) error {
+	existing := make(map[string]bool)
+	for _, f := range files {
+		if f.Task.ID != "" {
+			existing[f.Task.ID] = true
+		}
+	}
+	var unlock func()
+	defer func() {
+		if unlock != nil {
+			unlock()
+		}
+	}()
+	for _, f := range files {
+		if f.Task.ID != "" {
+			continue
+		}
+		if unlock == nil {
+			var err error
+			if unlock, err = utils.LockStore(store); err != nil {
+				return err
+			}
+		}
+		content, err := store.Get(f.Path)
+		if err != nil {
+			return fmt.Errorf("error reading file %s: %w", f.Path, err)
+		}
+		_, descriptionPart, err := parseTaskContent(content, format, f.Path)
+		if err != nil {
+			return err
+		}
+		id, err := NewID(existing)
+		if err != nil {
+			return err
+		}
+		f.Task.ID = id
+		newContent, err := renderTaskContent(content, format, f.Task, descriptionPart, f.Path)
+		if err != nil {
+			return err
+		}
+		if err := putIfUnchanged(store, f.Path, content, newContent); err != nil {
+			return fmt.Errorf("error backfilling ID for %s: %w", f.Path, err)
+		}
+		existing[id] = true
+		if idx != nil {
+			delete(idx.Entries, f.Path)
+			idx.dirty = true
+		}
+	}
+	return nil
+}
+
+// indexIsStale reports whether the note behind a task resolved from the index no longer
+// matches what the index says about it.
+func indexIsStale(store utils.TaskStore, format config.NoteFormat, found *taskFile) bool {
+	if _, ok := store.(utils.IndexableStore); !ok {
+		return false
+	}
+	content, err := store.Get(found.Path)
+	if err != nil {
+		return true
+	}
+	t, _, err := parseTaskContent(content, format, found.Path)
+	if err != nil {
+		return true
+	}
+	return t.ID != found.Task.ID || t.Issue != found.Task.Issue || t.Title != found.Task.Title || t.Status != found.Task.Status
+}
@@ -0,0 +1,162 @@
+package task
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// indexedStore returns a MarkdownStore in a temporary directory, whose index is cached
+// in another one.
+func indexedStore(t *testing.T, notes map[string]string) *utils.MarkdownStore {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	store := &utils.MarkdownStore{Dir: t.TempDir()}
+	for name, content := range notes {
+		writeNote(t, store, name, content)
+	}
+	return store
+}
+
+func writeNote(t *testing.T, store *utils.MarkdownStore, name, content string) {
+	t.Helper()
+	if err := store.Put(name, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scanTitles scans store and returns the title of each task by ID.
+func scanTitles(t *testing.T, store *utils.MarkdownStore) map[string]string {
+	t.Helper()
+	files, err := scanStore(store, config.NoteFormat{}, false)
+	if err != nil {
+		t.Fatalf("scanStore: %v", err)
+	}
+	titles := make(map[string]string)
+	for _, f := range files {
+		titles[f.Task.ID] = f.Task.Title
+	}
+	return titles
+}
+
+// editIndex changes the cached index of store without touching the notes.
+func editIndex(t *testing.T, store *utils.MarkdownStore, edit func(idx *taskIndex)) {
+	t.Helper()
+	idx := loadIndex(store, config.NoteFormat{}, false)
+	edit(idx)
+	idx.dirty = true
+	idx.save()
+}
+
+func TestIndexRefresh(t *testing.T) {
+	store := indexedStore(t, map[string]string{
+		"a.md": "---\nid: aaa111\ntitle: First\nstatus: todo\n---\n",
+		"b.md": "---\nid: bbb222\ntitle: Second\nstatus: todo\n---\n",
+	})
+	if got := scanTitles(t, store); len(got) != 2 {
+		t.Fatalf("first scan: %v", got)
+	}
+	idx := loadIndex(store, config.NoteFormat{}, false)
+	if len(idx.Entries) != 2 || idx.Entries["a.md"].Task.Title != "First" {
+		t.Fatalf("index not saved: %+v", idx.Entries)
+	}
+
+	// An unchanged note is read from the index, a changed one is parsed again.
+	editIndex(t, store, func(idx *taskIndex) {
+		for _, name := range []string{"a.md", "b.md"} {
+			entry := idx.Entries[name]
+			entry.Task.Title = "Cached"
+			idx.Entries[name] = entry
+		}
+	})
+	writeNote(t, store, "b.md", "---\nid: bbb222\ntitle: Second, renamed\nstatus: done\n---\n")
+	if got := scanTitles(t, store); got["aaa111"] != "Cached" || got["bbb222"] != "Second, renamed" {
+		t.Errorf("after changing b.md: %v", got)
+	}
+
+	// A removed note leaves the index, a new one enters it.
+	if err := store.Delete("a.md"); err != nil {
+		t.Fatal(err)
+	}
+	writeNote(t, store, "c.md", "---\nid: ccc333\ntitle: Third\nstatus: todo\n---\n")
+	if got := scanTitles(t, store); len(got) != 2 || got["ccc333"] != "Third" {
+		t.Errorf("after removing a.md and adding c.md: %v", got)
+	}
+	var names []string
+	for name := range loadIndex(store, config.NoteFormat{}, false).Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "b.md" || names[1] != "c.md" {
+		t.Errorf("indexed notes: %v", names)
+	}
+}
+
+func TestIndexVersionForcesRescan(t *testing.T) {
+	store := indexedStore(t, map[string]string{
+		"a.md": "---\nid: aaa111\ntitle: First\nstatus: todo\n---\n",
+	})
+	scanTitles(t, store)
+	editIndex(t, store, func(idx *taskIndex) {
+		entry := idx.Entries["a.md"]
+		entry.Task.Title = "Cached"
+		idx.Entries["a.md"] = entry
+		idx.Version = indexVersion - 1
+	})
+	if idx := loadIndex(store, config.NoteFormat{}, false); len(idx.Entries) != 0 || !idx.dirty {
+		t.Fatalf("an index of version %d was loaded: %+v", indexVersion-1, idx.Entries)
+	}
+	if got := scanTitles(t, store); got["aaa111"] != "First" {
+		t.Errorf("after a version bump: %v", got)
+	}
+	if idx := loadIndex(store, config.NoteFormat{}, false); idx.Entries["a.md"].Task.Title != "First" {
+		t.Errorf("the rescanned index was not saved: %+v", idx.Entries)
+	}
+}
+
+func TestInconsistentIndexIsRebuilt(t *testing.T) {
+	notes := map[string]string{
+		"a.md": "---\nid: aaa111\ntitle: First\nstatus: todo\n---\n",
+		"b.md": "---\nid: bbb222\ntitle: Second\nstatus: todo\n---\n",
+	}
+	store := indexedStore(t, notes)
+	scanTitles(t, store)
+	// The index claims both notes share an ID, which they do not.
+	editIndex(t, store, func(idx *taskIndex) {
+		entry := idx.Entries["b.md"]
+		entry.Task.ID = "aaa111"
+		idx.Entries["b.md"] = entry
+	})
+
+	if got := scanTitles(t, store); got["aaa111"] != "First" || got["bbb222"] != "Second" {
+		t.Errorf("after rebuilding: %v", got)
+	}
+	for name, content := range notes {
+		if got, _ := store.Get(name); string(got) != content {
+			t.Errorf("%s was rewritten:\n%s", name, got)
+		}
+	}
+	if idx := loadIndex(store, config.NoteFormat{}, false); idx.Entries["b.md"].Task.ID != "bbb222" {
+		t.Errorf("the rebuilt index was not saved: %+v", idx.Entries)
+	}
+}
+
+func TestCopiedNoteGetsNewID(t *testing.T) {
+	store := indexedStore(t, map[string]string{
+		"a.md": "---\nid: aaa111\ntitle: First\nstatus: todo\n---\n",
+	})
+	scanTitles(t, store)
+	writeNote(t, store, "b.md", "---\nid: aaa111\ntitle: First, copied\nstatus: todo\n---\n")
+
+	got := scanTitles(t, store)
+	if len(got) != 2 || got["aaa111"] != "First" {
+		t.Fatalf("after copying a note: %v", got)
+	}
+	// The new ID was written to the copy, and the next scan keeps it.
+	if again := scanTitles(t, store); !reflect.DeepEqual(again, got) {
+		t.Errorf("next scan: got %v, want %v", again, got)
+	}
+}
@@ -0,0 +1,85 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"tasky/forge"
+	"tasky/utils"
+)
+
+// finishProgress records how far FinishTask got with a branch, so that a finish that
+// failed halfway resumes there, even after the branch was deleted by the merge.
+type finishProgress struct {
+	PullRequest *forge.PullRequest `json:"pull_request,omitempty"`
+	Merged      bool               `json:"merged,omitempty"`
+}
+
+// finishState holds the progress of the unfinished branches of a repository, kept under
+// the user cache directory next to the task index.
+type finishState struct {
+	Branches map[string]*finishProgress `json:"branches"`
+
+	path string
+}
+
+// loadFinishState reads the finish progress of the repository of the current directory.
+// A missing or unreadable file yields no progress.
+func loadFinishState() *finishState {
+	state := &finishState{Branches: make(map[string]*finishProgress)}
+	toplevel, err := utils.RunCmd("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return state
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return state
+	}
+	sum := sha256.Sum256([]byte(toplevel))
+	state.path = filepath.Join(cacheDir, "tasky", "finish-"+hex.EncodeToString(sum[:8])+".json")
+
+	content, err := os.ReadFile(state.path)
+	if err != nil {
+		return state
+	}
+	var saved finishState
+	if err := json.Unmarshal(content, &saved); err == nil && saved.Branches != nil {
+		state.Branches = saved.Branches
+	}
+	return state
+}
+
+// unfinished returns the only branch with progress, or "" if there are none or several.
+func (s *finishState) unfinished() string {
+	if len(s.Branches) != 1 {
+		return ""
+	}
+	for branch := range s.Branches {
+		return branch
+	}
+	return ""
+}
+
+// save writes the progress back, removing the file once no branch is left unfinished.
+func (s *finishState) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if len(s.Branches) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	content, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(s.path, content, 0644)
+}
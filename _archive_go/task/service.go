@@ -0,0 +1,69 @@
+package task
+
+import (
+	"errors"
+
+	"tasky/config"
+	"tasky/session"
+	"tasky/utils"
+)
+
+// Errors returned, wrapped, by Service, to be told apart with errors.Is.
+var (
+	// ErrNotFound reports a task reference, or a project, matching nothing.
+	ErrNotFound = errors.New("not found")
+	// ErrAmbiguous reports a task reference matching several tasks.
+	ErrAmbiguous = errors.New("ambiguous")
+	// ErrAlreadyInState reports a task that already has the status it was moved to.
+	ErrAlreadyInState = errors.New("already in state")
+	// ErrInvalid reports a field value, or an edited note, that a task cannot have.
+	ErrInvalid = errors.New("invalid")
+)
+
+// Service runs the task operations of tasky for a configuration. Its methods never
+// prompt nor print: they return results, and errors wrapping the errors above, so that
+// tasky can be embedded in other Go tools. The cmd package is the one talking to the
+// user.
+type Service struct {
+	cfg config.Config
+}
+
+// NewService returns a Service working on the vault and projects of cfg.
+func NewService(cfg config.Config) *Service {
+	return &Service{cfg: cfg}
+}
+
+// Project returns the project of the current directory, see utils.GetProjectName.
+func (s *Service) Project() string {
+	return utils.GetProjectName(s.cfg)
+}
+
+// Projects returns the projects of the vault.
+func (s *Service) Projects() ([]string, error) {
+	return ListProjects(s.cfg)
+}
+
+// List returns the tasks of projectName, or of every project when it is empty, see
+// ListEntries.
+func (s *Service) List(projectName string, withDescription bool) ([]Entry, error) {
+	return ListEntries(s.cfg, projectName, withDescription)
+}
+
+// Find returns the task of projectName matching ref, see FindTask.
+func (s *Service) Find(projectName, ref string) (*Entry, error) {
+	t, path, err := FindTask(s.cfg, projectName, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Task: *t, Project: projectName, Path: path}, nil
+}
+
+// ActiveTask returns the task being worked on, or nil, see FindActiveTask.
+func (s *Service) ActiveTask() (*Entry, error) {
+	return FindActiveTask(s.cfg)
+}
+
+// RecordSession appends a Pomodoro session to the log, see RecordSession.
+func (s *Service) RecordSession(sess session.Session) error {
+	return RecordSession(s.cfg, sess)
+}
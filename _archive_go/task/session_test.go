@@ -0,0 +1,84 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tasky/session"
+)
+
+func TestRecordSession(t *testing.T) {
+	o := newOffline(t)
+	o.cfg.Pomodoro.SessionLog = filepath.Join(t.TempDir(), "sessions.ndjson")
+	o.svc = NewService(o.cfg)
+	o.play(t)
+	created, err := o.svc.Create("Write docs", CreateOptions{CreateProject: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// Counted before sessions were logged.
+	if _, err := o.svc.Set("proj", created.ID, []Field{{"pomodoro_count", "2"}, {"duration", "50"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	_, before, err := o.svc.ReadNote("proj", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	record := func(kind session.Kind, completed bool, elapsed time.Duration) {
+		t.Helper()
+		s := session.Session{Start: start, End: start.Add(elapsed), Kind: kind, Project: "proj", TaskID: created.ID, Completed: completed, Elapsed: elapsed}
+		if err := o.svc.RecordSession(s); err != nil {
+			t.Fatalf("RecordSession(%s): %v", kind, err)
+		}
+		start = start.Add(elapsed)
+	}
+	assertCounts := func(pomodoros, minutes int) {
+		t.Helper()
+		e, err := o.svc.Find("proj", created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.PomodoroCount != pomodoros || e.Duration != minutes {
+			t.Errorf("got %d Pomodoros and %d minutes, want %d and %d", e.PomodoroCount, e.Duration, pomodoros, minutes)
+		}
+	}
+
+	record(session.KindShortBreak, true, 5*time.Minute)
+	_, after, err := o.svc.ReadNote("proj", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("a break changed the note:\n%s", after)
+	}
+
+	record(session.KindWork, true, 25*time.Minute)
+	assertCounts(3, 75)
+	record(session.KindLongBreak, true, 15*time.Minute)
+	record(session.KindWork, false, 10*time.Minute)
+	assertCounts(3, 85)
+
+	sessions, err := session.Read(o.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []session.Kind
+	for _, s := range sessions {
+		kinds = append(kinds, s.Kind)
+	}
+	want := []session.Kind{session.KindShortBreak, session.KindBaseline, session.KindWork, session.KindLongBreak, session.KindWork}
+	if len(kinds) != len(want) {
+		t.Fatalf("logged %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("logged %v, want %v", kinds, want)
+		}
+	}
+	if pomodoros, minutes := session.Totals(session.ForTask(sessions, "proj", created.ID)); pomodoros != 3 || minutes != 85 {
+		t.Errorf("log totals: %d Pomodoros and %d minutes", pomodoros, minutes)
+	}
+}
@@ -2,26 +2,60 @@ package task
 
 import (
 	"fmt"
-		"os/exec"
 
+	"tasky/config"
+	"tasky/forge"
 	"tasky/utils"
 )
 
-func StartTaskDevelopment(issueNumber string) error {
+// StartDevelopment checks out the branch for working on a task, named after
+// branch.template, and records it in the task so that Pomodoros on that branch are
+// attributed to it even without an issue. With an issue, the forge of the repository
+// creates the branch; foundTask may then be nil to work on an issue without a note. It
+// returns the branch.
+func (s *Service) StartDevelopment(foundTask *config.Task, issueNumber int, branchType string) (string, error) {
 	if !utils.IsGitRepository() {
-		return fmt.Errorf("not in a Git repository. Cannot start development on a GitHub issue.")
+		return "", fmt.Errorf("not in a Git repository. Cannot start development on a task.")
 	}
 
-	cmd := exec.Command("gh", "issue", "develop", issueNumber, "--checkout")
-	cmd.Stdout = utils.NewLogWriter("gh issue develop", false)
-	cmd.Stderr = utils.NewLogWriter("gh issue develop", true)
-
-	fmt.Printf("Starting development for issue #%s...\n", issueNumber)
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("error starting development for issue #%s: %w", issueNumber, err)
+	var branch string
+	if issueNumber == 0 {
+		branch = utils.BranchName(s.cfg, branchType, 0, foundTask.ID, foundTask.Title)
+		if err := utils.CheckoutBranch(branch); err != nil {
+			return "", fmt.Errorf("error checking out branch %s: %w", branch, err)
+		}
+	} else {
+		f, err := forge.New(s.cfg)
+		if err != nil {
+			return "", err
+		}
+		issue, err := f.ViewIssue(issueNumber)
+		if err != nil {
+			return "", fmt.Errorf("error getting %s issue #%d: %w", f.Name(), issueNumber, err)
+		}
+		id := ""
+		if foundTask != nil {
+			id = foundTask.ID
+		}
+		branch = utils.BranchName(s.cfg, branchType, issueNumber, id, issue.Title)
+		if err := f.DevelopIssue(issue, branch); err != nil {
+			return "", fmt.Errorf("error starting development for issue #%d: %w", issueNumber, err)
+		}
 	}
 
-	fmt.Printf("Successfully started development for issue #%s.\n", issueNumber)
-	return nil
-}
\ No newline at end of file
+	if foundTask != nil && foundTask.Branch != branch {
+		projectName := utils.GetProjectName(s.cfg)
+		_, foundPath, err := FindTask(s.cfg, projectName, foundTask.ID)
+		if err == nil {
+			_, err = updateTask(s.cfg, projectName, foundPath, func(t *config.Task) error {
+				t.Branch = branch
+				return nil
+			})
+		}
+		if err != nil {
+			return branch, fmt.Errorf("error linking the task to branch %s: %w", branch, err)
+		}
+		foundTask.Branch = branch
+	}
+	return branch, nil
+}
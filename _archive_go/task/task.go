@@ -1,16 +1,14 @@
 package task
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"regexp"
+	"io/fs"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
-
 	"tasky/config"
+	"tasky/session"
 	"tasky/utils"
 )
 
@@ -21,322 +19,293 @@ func ReadTaskFile(cfg config.Config, projectName string, filePath string) (*conf
 	if err != nil {
 		return nil, "", fmt.Errorf("error reading file %s: %w", filePath, err)
 	}
+	return parseTaskContent(fullContent, cfg.NoteFormat, filePath)
+}
 
-	text := string(fullContent)
-
-	// Extract YAML frontmatter directly
-	re := regexp.MustCompile(`(?s)^---\n(.*?)\n---\n`)
-	matches := re.FindStringSubmatch(text)
-
-	var yamlContent string
-	if len(matches) > 1 {
-		yamlContent = matches[1]
-	} else {
+// parseTaskContent splits a note into its frontmatter, decoded into a config.Task, and its markdown content.
+func parseTaskContent(fullContent []byte, format config.NoteFormat, filePath string) (*config.Task, string, error) {
+	parts, ok := splitNote(fullContent)
+	if !ok {
 		return nil, "", fmt.Errorf("no YAML frontmatter found in %s", filePath)
 	}
 
-	var t config.Task
-	if err := yaml.Unmarshal([]byte(yamlContent), &t); err != nil {
+	t, err := decodeFrontmatter(parts.frontmatter, format)
+	if err != nil {
 		return nil, "", fmt.Errorf("error unmarshalling YAML from %s: %w", filePath, err)
 	}
-
-	endYaml := strings.Index(text[3:], "---") + 3
-	descriptionPart := strings.TrimSpace(text[endYaml+3:])
-
-	return &t, descriptionPart, nil
+	return t, strings.TrimSpace(parts.body), nil
 }
 
-// WriteTaskFile marshals a config.Task back into YAML frontmatter, combines it with the markdown content,
-// and writes it back to the file.
+// WriteTaskFile writes a task's frontmatter and markdown content to a note. An existing
+// note is updated in place: only the frontmatter keys tasky owns are touched, and the
+// content is kept as it is unless it changed. The note is written atomically while
+// holding the store's lock.
 func WriteTaskFile(cfg config.Config, projectName string, filePath string, task *config.Task, descriptionPart string) error {
-	updatedYamlData, err := yaml.Marshal(&task)
+	store, err := utils.OpenTaskStore(cfg, projectName)
 	if err != nil {
-		return fmt.Errorf("error marshalling updated YAML for %s: %w", filePath, err)
+		return fmt.Errorf("error opening task store: %w", err)
 	}
-
-	newContent := fmt.Sprintf("---\n%s---\n\n%s", string(updatedYamlData), descriptionPart)
-
-	return utils.WriteToTaskyFile(cfg, projectName, filePath, []byte(newContent))
-}
-
-func GetTasks(cfg config.Config, filterProject string) []config.Task {
-	var tasks []config.Task
-	projectName := utils.GetProjectName()
-	taskyBaseDir, err := utils.GetTaskyDir(cfg, projectName)
+	unlock, err := utils.LockStore(store)
 	if err != nil {
-		fmt.Println("Error getting Tasky directory:", err)
-		return tasks
+		return err
 	}
+	defer unlock()
 
-	var walkPath string
-	if filterProject != "" {
-		walkPath = filepath.Join(taskyBaseDir, filterProject)
-	} else {
-		walkPath = taskyBaseDir
+	original, err := store.Get(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error reading file %s: %w", filePath, err)
 	}
-
-	// Ensure the directory exists before trying to walk it
-	if _, err := os.Stat(walkPath); os.IsNotExist(err) {
-		return tasks
+	newContent, err := renderTaskContent(original, cfg.NoteFormat, task, descriptionPart, filePath)
+	if err != nil {
+		return err
 	}
+	return store.Put(filePath, newContent)
+}
 
-	err = filepath.Walk(walkPath, func(path string, info os.FileInfo, err error) error {
+// renderTaskContent builds the full note text from a task's frontmatter and its markdown
+// content. When the note already exists, original is its current content, which is
+// updated rather than replaced.
+func renderTaskContent(original []byte, format config.NoteFormat, task *config.Task, descriptionPart string, filePath string) ([]byte, error) {
+	if parts, ok := splitNote(original); ok {
+		frontmatter, err := updateFrontmatter(parts.frontmatter, task, format, parts.newline)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("error updating YAML frontmatter of %s: %w", filePath, err)
 		}
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
-			return nil
+		body := parts.body
+		if strings.TrimSpace(body) != descriptionPart {
+			body = parts.newline + descriptionPart
 		}
+		return []byte(parts.open + frontmatter + parts.close + body), nil
+	}
 
-		t, _, err := ReadTaskFile(cfg, projectName, path)
-		if err == nil {
-			tasks = append(tasks, *t)
-		}
-
-		return nil
-	})
-
+	frontmatter, err := renderFrontmatter(task, format)
 	if err != nil {
-		fmt.Println("Error reading:", err)
+		return nil, fmt.Errorf("error marshalling updated YAML for %s: %w", filePath, err)
 	}
-
-	return tasks
+	return []byte(fmt.Sprintf("---\n%s---\n\n%s", frontmatter, descriptionPart)), nil
 }
 
-func MarkTaskDone(cfg config.Config, taskTitle string) error {
-	var foundTask *config.Task
-	var foundPath string
+// Entry is a task together with the project and store name it was read from. File is
+// the note's location on disk when the store keeps one file per note. Description holds
+// the note's markdown content and is only filled in on request.
+type Entry struct {
+	config.Task
+	Project     string
+	Path        string
+	File        string
+	Description string
+}
 
-	projectName := utils.GetProjectName()
-	taskyBaseDir, err := utils.GetTaskyDir(cfg, projectName)
-	if err != nil {
-		return fmt.Errorf("error getting Tasky directory: %w", err)
+// ListEntries returns the tasks of filterProject, or of every project when filterProject
+// is empty. Reading descriptions requires opening every note, so it is opt-in.
+func ListEntries(cfg config.Config, filterProject string, withDescription bool) ([]Entry, error) {
+	projects := []string{filterProject}
+	if filterProject == "" {
+		var err error
+		projects, err = ListProjects(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vault: %w", err)
+		}
 	}
 
-	err = filepath.Walk(taskyBaseDir, func(path string, info os.FileInfo, err error) error {
+	var entries []Entry
+	for _, projectName := range projects {
+		store, err := utils.OpenTaskStore(cfg, projectName)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("error opening task store: %w", err)
 		}
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
-			return nil
+		files, err := scanStore(store, cfg.NoteFormat, false)
+		if err != nil {
+			return nil, err
 		}
-
-		t, _, err := ReadTaskFile(cfg, projectName, path)
-		if err == nil {
-			if strings.EqualFold(t.Title, taskTitle) {
-				foundTask = t
-				foundPath = path
-				return filepath.SkipDir // Found the task, stop walking
+		locatable, _ := store.(utils.LocatableStore)
+		for _, f := range files {
+			entry := Entry{Task: *f.Task, Project: projectName, Path: f.Path}
+			if locatable != nil {
+				entry.File = locatable.Location(f.Path)
 			}
+			if withDescription {
+				content, err := store.Get(f.Path)
+				if err != nil {
+					return nil, fmt.Errorf("error reading file %s: %w", f.Path, err)
+				}
+				if _, entry.Description, err = parseTaskContent(content, cfg.NoteFormat, f.Path); err != nil {
+					return nil, err
+				}
+			}
+			entries = append(entries, entry)
 		}
-
-		return nil
-	})
-
-	if err != nil && err != filepath.SkipDir {
-		return fmt.Errorf("error searching for task: %w", err)
-	}
-
-	if foundTask == nil {
-		return fmt.Errorf("task '%s' not found", taskTitle)
-	}
-
-	if foundTask.Status == config.StatusDone {
-		return fmt.Errorf("task '%s' is already marked as done", taskTitle)
 	}
-
-	foundTask.Status = config.StatusDone
-	foundTask.DoneDate = time.Now().Format("2006-01-02")
-
-	_, descriptionPart, err := ReadTaskFile(cfg, projectName, foundPath)
-	if err != nil {
-		return fmt.Errorf("error reading task file for update: %w", err)
-	}
-
-	if err := WriteTaskFile(cfg, projectName, foundPath, foundTask, descriptionPart); err != nil {
-		return fmt.Errorf("error writing updated task file: %w", err)
-	}
-
-	return nil
+	return entries, nil
 }
 
-func MarkTaskInProgress(cfg config.Config, issueNumber int) {
-	var foundTask *config.Task
-	var foundPath string
+// MarkDone marks the task of projectName matching ref (ID, ID prefix, issue number or
+// title) as done, see SetStatus.
+func (s *Service) MarkDone(projectName, ref string) (*Entry, error) {
+	return s.SetStatus(projectName, ref, config.StatusDone)
+}
 
-	projectName := utils.GetProjectName()
-	taskyBaseDir, err := utils.GetTaskyDir(cfg, projectName)
+// MarkInProgress marks the task of projectName matching ref as in progress, starting it
+// now. It returns the updated task, or an error wrapping ErrAlreadyInState if the task
+// was already in progress.
+func (s *Service) MarkInProgress(projectName, ref string) (*Entry, error) {
+	_, foundPath, err := FindTask(s.cfg, projectName, ref)
 	if err != nil {
-		fmt.Println("Error getting Tasky directory:", err)
-		return
+		return nil, err
 	}
-
-	err = filepath.Walk(taskyBaseDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
-			return nil
+	updated, err := updateTask(s.cfg, projectName, foundPath, func(t *config.Task) error {
+		if t.Status == config.StatusInProgress {
+			return fmt.Errorf("task '%s' is %w '%s'", t.Title, ErrAlreadyInState, t.Status)
 		}
-
-		t, _, err := ReadTaskFile(cfg, projectName, path)
-		if err == nil {
-			if t.Issue == issueNumber {
-				foundTask = t
-				foundPath = path
-				return filepath.SkipDir // Found the task, stop walking
-			}
-		}
-
+		t.Status = config.StatusInProgress
+		t.StartDate = time.Now().Format(config.DateTimeLayout)
 		return nil
 	})
-
-	if err != nil && err != filepath.SkipDir {
-		fmt.Println("Error searching for task:", err)
-		return
-	}
-
-	if foundTask == nil {
-		fmt.Printf("Task with GitHub issue #%d not found.\n", issueNumber)
-		return
-	}
-
-	if foundTask.Status == config.StatusInProgress {
-		fmt.Printf("Task '%s' is already marked as in-progress.\n", foundTask.Title)
-		return
+	if err != nil {
+		return nil, err
 	}
+	return &Entry{Task: *updated, Project: projectName, Path: foundPath}, nil
+}
 
-	foundTask.Status = config.StatusInProgress
-	foundTask.StartDate = time.Now().Format("2006-01-02 15:04:05")
-
-	_, descriptionPart, err := ReadTaskFile(cfg, projectName, foundPath)
+// SetStatus moves the task of projectName matching ref to status, keeping its start and
+// done dates consistent with the new status. It returns the updated task, or an error
+// wrapping ErrAlreadyInState if the task already had that status.
+func (s *Service) SetStatus(projectName, ref, status string) (*Entry, error) {
+	_, foundPath, err := FindTask(s.cfg, projectName, ref)
 	if err != nil {
-		fmt.Println("Error reading task file for update:", err)
-		return
+		return nil, err
 	}
-
-	if err := WriteTaskFile(cfg, projectName, foundPath, foundTask, descriptionPart); err != nil {
-		fmt.Println("Error writing updated task file:", err)
-		return
+	updated, err := setStatus(s.cfg, projectName, foundPath, status)
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Printf("Task '%s' marked as in-progress.\n", foundTask.Title)
+	return &Entry{Task: *updated, Project: projectName, Path: foundPath}, nil
 }
 
-// MarkTaskInProgressByTitle marks a task as in-progress using its title.
-func MarkTaskInProgressByTitle(cfg config.Config, taskTitle string) {
-	var foundTask *config.Task
-	var foundPath string
-
-	projectName := utils.GetProjectName()
-	taskyBaseDir, err := utils.GetTaskyDir(cfg, projectName)
+// SetTaskStatus moves a task of projectName to status like Service.SetStatus, but a task
+// already in that status is not an error. It returns the updated task.
+func SetTaskStatus(cfg config.Config, projectName string, taskRef string, status string) (*config.Task, error) {
+	_, foundPath, err := FindTask(cfg, projectName, taskRef)
 	if err != nil {
-		fmt.Println("Error getting Tasky directory:", err)
-		return
+		return nil, err
 	}
+	updated, err := setStatus(cfg, projectName, foundPath, status)
+	if err != nil && !errors.Is(err, ErrAlreadyInState) {
+		return nil, err
+	}
+	return updated, nil
+}
 
-	err = filepath.Walk(taskyBaseDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
-			return nil
-		}
-
-		t, _, err := ReadTaskFile(cfg, projectName, path)
-		if err == nil {
-			if strings.EqualFold(t.Title, taskTitle) {
-				foundTask = t
-				foundPath = path
-				return filepath.SkipDir // Found the task, stop walking
-			}
+// setStatus moves the task of the note at path to status.
+func setStatus(cfg config.Config, projectName, path, status string) (*config.Task, error) {
+	return updateTask(cfg, projectName, path, func(t *config.Task) error {
+		if t.Status == status {
+			return fmt.Errorf("task '%s' is %w '%s'", t.Title, ErrAlreadyInState, status)
 		}
-
-		return nil
+		return applyStatus(t, status)
 	})
+}
 
-	if err != nil && err != filepath.SkipDir {
-		fmt.Println("Error searching for task:", err)
-		return
+// FindActiveTask returns the task being worked on: the task linked to the current branch,
+// or else the task of the issue number in the branch name, or nil when there is none.
+func FindActiveTask(cfg config.Config) (*Entry, error) {
+	branchName, err := utils.GetCurrentBranchName()
+	if err != nil {
+		return nil, nil // Not in a Git repository
 	}
-
-	if foundTask == nil {
-		fmt.Printf("Task '%s' not found.\n", taskTitle)
-		return
+	projectName := utils.GetProjectName(cfg)
+	if e, err := findBranchTask(cfg, projectName, branchName); e != nil || err != nil {
+		return e, err
 	}
-
-	if foundTask.Status == config.StatusInProgress {
-		fmt.Printf("Task '%s' is already marked as in-progress.\n", taskTitle)
-		return
+	issueStr := utils.ExtractIssueNumberFromBranch(cfg, branchName)
+	if issueStr == "" {
+		return nil, nil // No issue detected
+	}
+	foundTask, foundPath, err := FindTask(cfg, projectName, "#"+issueStr)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil // No task found
 	}
-
-	foundTask.Status = config.StatusInProgress
-	foundTask.StartDate = time.Now().Format("2006-01-02 15:04:05")
-
-	_, descriptionPart, err := ReadTaskFile(cfg, projectName, foundPath)
 	if err != nil {
-		fmt.Println("Error reading task file for update:", err)
-		return
+		return nil, err
 	}
+	return &Entry{Task: *foundTask, Project: projectName, Path: foundPath}, nil
+}
 
-	if err := WriteTaskFile(cfg, projectName, foundPath, foundTask, descriptionPart); err != nil {
-		fmt.Println("Error writing updated task file:", err)
-		return
+// findBranchTask returns the task of projectName linked to branch, preferring one that
+// is not done, or nil if there is none.
+func findBranchTask(cfg config.Config, projectName, branch string) (*Entry, error) {
+	entries, err := ListEntries(cfg, projectName, false)
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Printf("Task '%s' marked as in-progress.\n", taskTitle)
+	var found *Entry
+	for i, e := range entries {
+		if e.Branch != branch {
+			continue
+		}
+		if found == nil || found.Status == config.StatusDone && e.Status != config.StatusDone {
+			found = &entries[i]
+		}
+	}
+	return found, nil
 }
 
-// IncrementPomodoroCountForActiveTask increments the Pomodoro counter of the active task (from the current branch issue)
-func IncrementPomodoroCountForActiveTask(cfg config.Config) error {
-	branchName, err := utils.GetCurrentBranchName()
+// RecordSession appends s to the session log. When a work session belongs to a task,
+// the task's Pomodoro count and duration are then derived again from all of its
+// sessions. The log stays locked until the task is updated.
+func RecordSession(cfg config.Config, s session.Session) error {
+	unlock, err := session.Lock(cfg)
 	if err != nil {
 		return err
 	}
-	issueStr := utils.ExtractIssueNumberFromBranch(branchName)
-	if issueStr == "" {
-		return nil // No issue detected
+	defer unlock()
+	// Breaks count for neither the Pomodoros nor the duration of a task.
+	if s.TaskID == "" || s.Kind != session.KindWork {
+		return session.Append(cfg, s)
 	}
-	var issueNumber int
-	_, err = fmt.Sscanf(issueStr, "%d", &issueNumber)
+
+	foundTask, foundPath, err := FindTask(cfg, s.Project, s.TaskID)
 	if err != nil {
 		return err
 	}
-	projectName := utils.GetProjectName()
-	projectTasksPath, err := utils.GetTaskyDir(cfg, projectName)
+	sessions, err := session.Read(cfg)
 	if err != nil {
 		return err
 	}
-	var foundPath string
-	var foundTask *config.Task
-	filepath.Walk(projectTasksPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
-			return nil
+	taskSessions := session.ForTask(sessions, s.Project, foundTask.ID)
+
+	// Keep what was counted before the task had any logged work session; breaks may
+	// have been logged for it already.
+	counted := false
+	for _, logged := range taskSessions {
+		counted = counted || logged.Kind == session.KindWork || logged.Kind == session.KindBaseline
+	}
+	if !counted && (foundTask.PomodoroCount > 0 || foundTask.Duration > 0) {
+		baseline := session.Session{
+			Start:     s.Start,
+			End:       s.Start,
+			Kind:      session.KindBaseline,
+			Project:   s.Project,
+			TaskID:    foundTask.ID,
+			Completed: true,
+			Elapsed:   time.Duration(foundTask.Duration) * time.Minute,
+			Pomodoros: foundTask.PomodoroCount,
 		}
-
-		t, _, err := ReadTaskFile(cfg, projectName, path)
-		if err == nil && t.Issue == issueNumber {
-			foundTask = t
-			foundPath = path
-			return filepath.SkipDir
+		if err := session.Append(cfg, baseline); err != nil {
+			return err
 		}
-		return nil
-	})
-	if foundTask == nil {
-		return nil // No task found
-	}
-	foundTask.PomodoroCount++
-	// Update Duration (add PomodoroDuration minutes)
-	minutesToAdd := 25 // default
-	if cfg.Pomodoro.PomodoroDuration > 0 {
-		minutesToAdd = cfg.Pomodoro.PomodoroDuration
+		taskSessions = append(taskSessions, baseline)
 	}
-	foundTask.Duration += minutesToAdd
 
-	_, descriptionPart, err := ReadTaskFile(cfg, projectName, foundPath)
-	if err != nil {
+	if err := session.Append(cfg, s); err != nil {
 		return err
 	}
+	taskSessions = append(taskSessions, s)
+	pomodoros, minutes := session.Totals(taskSessions)
 
-	return WriteTaskFile(cfg, projectName, foundPath, foundTask, descriptionPart)
-}
\ No newline at end of file
+	_, err = updateTask(cfg, s.Project, foundPath, func(t *config.Task) error {
+		t.PomodoroCount, t.Duration = pomodoros, minutes
+		return nil
+	})
+	return err
+}
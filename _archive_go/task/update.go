@@ -0,0 +1,106 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"tasky/config"
+	"tasky/utils"
+)
+
+// maxUpdateAttempts is how many times updateTask applies a change to a note that keeps
+// being modified by another program before giving up.
+const maxUpdateAttempts = 3
+
+// errNoteChanged reports a note modified by someone else between reading and writing it.
+var errNoteChanged = errors.New("the note was modified by another program")
+
+// updateTask reads the task stored in a note, applies change to it and writes the note
+// back, holding the store's lock so that another tasky process cannot interleave its own
+// update. Programs that ignore the lock, such as Obsidian or a sync client, are detected
+// by comparing the note with what was read just before writing it; the change is then
+// applied again to the new content, since change may run more than once it must only
+// modify the task. If change returns an error, the note is left untouched and the task
+// is returned with that error.
+func updateTask(cfg config.Config, projectName, name string, change func(t *config.Task) error) (*config.Task, error) {
+	return updateNote(cfg, projectName, name, func(t *config.Task, _ *string) error {
+		return change(t)
+	})
+}
+
+// updateNote is updateTask for changes that may also modify the markdown content of the
+// note, passed as description.
+func updateNote(cfg config.Config, projectName, name string, change func(t *config.Task, description *string) error) (*config.Task, error) {
+	store, err := utils.OpenTaskStore(cfg, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening task store: %w", err)
+	}
+	unlock, err := utils.LockStore(store)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for attempt := 1; ; attempt++ {
+		content, err := store.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading task file %s: %w", name, err)
+		}
+		t, descriptionPart, err := parseTaskContent(content, cfg.NoteFormat, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := change(t, &descriptionPart); err != nil {
+			return t, err
+		}
+		newContent, err := renderTaskContent(content, cfg.NoteFormat, t, descriptionPart, name)
+		if err != nil {
+			return nil, err
+		}
+		err = putIfUnchanged(store, name, content, newContent)
+		if err == nil {
+			return t, nil
+		}
+		if !errors.Is(err, errNoteChanged) || attempt == maxUpdateAttempts {
+			return nil, fmt.Errorf("error writing task file %s: %w", name, err)
+		}
+	}
+}
+
+// putIfUnchanged writes content under name unless the stored note no longer is original.
+func putIfUnchanged(store utils.TaskStore, name string, original, content []byte) error {
+	current, err := store.Get(name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, original) {
+		return errNoteChanged
+	}
+	return store.Put(name, content)
+}
+
+// previewTask returns the content of a note before and after applying change to its
+// task, as updateTask would write it, without writing anything.
+func previewTask(cfg config.Config, projectName, name string, change func(t *config.Task) error) ([]byte, []byte, error) {
+	store, err := utils.OpenTaskStore(cfg, projectName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening task store: %w", err)
+	}
+	content, err := store.Get(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading task file %s: %w", name, err)
+	}
+	t, descriptionPart, err := parseTaskContent(content, cfg.NoteFormat, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := change(t); err != nil {
+		return nil, nil, err
+	}
+	newContent, err := renderTaskContent(content, cfg.NoteFormat, t, descriptionPart, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, newContent, nil
+}
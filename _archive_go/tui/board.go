@@ -0,0 +1,568 @@
+// Package tui implements `tasky ui`, a full-screen kanban board of the tasks.
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tasky/config"
+	"tasky/pomodoro"
+	"tasky/task"
+	"tasky/utils"
+)
+
+// ANSI escape sequences used to draw the board.
+const (
+	enterAltScreen = "\x1b[?1049h"
+	leaveAltScreen = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	clearScreen    = "\x1b[H\x1b[2J"
+	bold           = "\x1b[1m"
+	reverse        = "\x1b[7m"
+	dim            = "\x1b[2m"
+	reset          = "\x1b[0m"
+)
+
+const helpLine = "←→/hl column  ↑↓/jk select  </> move  n new  e edit  p pomodoro/pause  s skip  a stop  +/- minute  r reload  q quit"
+
+// statuses are the board's columns, left to right.
+var statuses = []string{config.StatusTodo, config.StatusInProgress, config.StatusDone}
+
+// pomodoroCycle is the Pomodoro cycle running on a task of the board. Its machine is
+// idle between phases while waiting for the user, unless AutoAdvance is set.
+type pomodoroCycle struct {
+	entry   task.Entry
+	machine *pomodoro.Machine
+}
+
+// Board is the state of the kanban board.
+type Board struct {
+	cfg     config.Config
+	project string // empty when showing every project
+
+	columns  [][]task.Entry
+	col      int
+	rows     []int
+	message  string
+	input    *string // text being typed for a new task, nil when not typing
+	pomodoro *pomodoroCycle
+	clock    pomodoro.Clock
+	// soundErrs receives the errors of the sounds played in the background.
+	soundErrs chan error
+}
+
+// newBoard returns the board of project, whose Pomodoros are timed by clock.
+func newBoard(cfg config.Config, project string, clock pomodoro.Clock) *Board {
+	return &Board{cfg: cfg, project: project, rows: make([]int, len(statuses)), clock: clock, soundErrs: make(chan error, 4)}
+}
+
+// Run shows the board of project, or of every project when project is empty, until the
+// user quits.
+func Run(cfg config.Config, project string) error {
+	if !utils.IsTerminal() {
+		return fmt.Errorf("tasky ui needs an interactive terminal")
+	}
+
+	b := newBoard(cfg, project, pomodoro.SystemClock)
+	if err := b.reload(); err != nil {
+		return err
+	}
+
+	restore, err := b.enterScreen()
+	if err != nil {
+		return err
+	}
+	defer func() { restore() }()
+
+	lastTick := time.Now()
+	b.draw()
+	for {
+		key := utils.ReadKey()
+		if key != "" {
+			quit, err := b.handleKey(key, &restore)
+			if err != nil {
+				return err
+			}
+			if quit {
+				b.stopPomodoro()
+				return nil
+			}
+			b.draw()
+		}
+		if time.Since(lastTick) >= time.Second {
+			lastTick = time.Now()
+			b.tick()
+			b.draw()
+		}
+	}
+}
+
+// enterScreen switches to raw mode and the alternate screen. The returned function undoes both.
+func (b *Board) enterScreen() (func(), error) {
+	restoreTerm, err := utils.MakeRaw()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Print(enterAltScreen + hideCursor)
+	return func() {
+		fmt.Print(showCursor + leaveAltScreen)
+		restoreTerm()
+	}, nil
+}
+
+// reload reads the tasks again, keeping the selection on the same task when possible.
+func (b *Board) reload() error {
+	selectedID := ""
+	if e := b.selected(); e != nil {
+		selectedID = e.ID
+	}
+
+	entries, err := task.ListEntries(b.cfg, b.project, false)
+	if err != nil {
+		return err
+	}
+	b.columns = make([][]task.Entry, len(statuses))
+	for _, e := range entries {
+		col := columnOf(e.Status)
+		b.columns[col] = append(b.columns[col], e)
+	}
+
+	for col, entries := range b.columns {
+		for row, e := range entries {
+			if e.ID == selectedID {
+				b.col, b.rows[col] = col, row
+			}
+		}
+		b.clampRow(col)
+	}
+	return nil
+}
+
+// columnOf returns the column of a status. Unknown statuses are shown as todo.
+func columnOf(status string) int {
+	for i, s := range statuses {
+		if s == status {
+			return i
+		}
+	}
+	return 0
+}
+
+func (b *Board) clampRow(col int) {
+	if b.rows[col] >= len(b.columns[col]) {
+		b.rows[col] = len(b.columns[col]) - 1
+	}
+	if b.rows[col] < 0 {
+		b.rows[col] = 0
+	}
+}
+
+// selected returns the task under the cursor, or nil when the column is empty.
+func (b *Board) selected() *task.Entry {
+	if b.columns == nil || len(b.columns[b.col]) == 0 {
+		return nil
+	}
+	return &b.columns[b.col][b.rows[b.col]]
+}
+
+// handleKey applies a keypress and reports whether the board should close. restore is
+// updated when the screen has to be left temporarily, e.g. for the editor.
+func (b *Board) handleKey(key string, restore *func()) (bool, error) {
+	if b.input != nil {
+		b.handleInputKey(key)
+		return false, nil
+	}
+
+	b.message = ""
+	switch key {
+	case "q", utils.KeyCtrlC:
+		return true, nil
+	case utils.KeyLeft, "h":
+		b.col = (b.col + len(statuses) - 1) % len(statuses)
+	case utils.KeyRight, "l":
+		b.col = (b.col + 1) % len(statuses)
+	case utils.KeyUp, "k":
+		if b.rows[b.col] > 0 {
+			b.rows[b.col]--
+		}
+	case utils.KeyDown, "j":
+		if b.rows[b.col] < len(b.columns[b.col])-1 {
+			b.rows[b.col]++
+		}
+	case "<", "H":
+		b.moveSelected(-1)
+	case ">", "L":
+		b.moveSelected(1)
+	case "n":
+		text := ""
+		b.input = &text
+	case "e":
+		return false, b.editSelected(restore)
+	case "p", " ":
+		b.togglePomodoro()
+	case "s":
+		b.skipPhase()
+	case "a":
+		b.stopPomodoro()
+	case "+", "=":
+		if b.pomodoro != nil {
+			b.pomodoro.machine.Extend(time.Minute)
+		}
+	case "-":
+		if b.pomodoro != nil {
+			b.pomodoro.machine.Extend(-time.Minute)
+		}
+	case "r":
+		if err := b.reload(); err != nil {
+			b.message = err.Error()
+		}
+	}
+	return false, nil
+}
+
+func (b *Board) handleInputKey(key string) {
+	switch key {
+	case utils.KeyEscape, utils.KeyCtrlC:
+		b.input = nil
+	case utils.KeyEnter:
+		title := strings.TrimSpace(*b.input)
+		b.input = nil
+		if title != "" {
+			b.createTask(title)
+		}
+	case utils.KeyBackspace:
+		runes := []rune(*b.input)
+		if len(runes) > 0 {
+			*b.input = string(runes[:len(runes)-1])
+		}
+	default:
+		if !strings.HasPrefix(key, utils.KeyEscape) {
+			*b.input += key
+		}
+	}
+}
+
+// projectOf returns the project new tasks are created in.
+func (b *Board) projectOf() string {
+	if b.project != "" {
+		return b.project
+	}
+	return utils.GetProjectName(b.cfg)
+}
+
+// createTask adds a task to the current column.
+func (b *Board) createTask(title string) {
+	now := time.Now()
+	t := config.Task{Frontmatter: config.Frontmatter{
+		Title:       title,
+		Status:      statuses[b.col],
+		CreatedDate: now.Format(config.DateTimeLayout),
+	}}
+	switch t.Status {
+	case config.StatusInProgress:
+		t.StartDate = now.Format(config.DateTimeLayout)
+	case config.StatusDone:
+		t.DoneDate = now.Format(config.DateLayout)
+	}
+	if _, err := task.CreateTaskNote(b.cfg, b.projectOf(), &t, ""); err != nil {
+		b.message = fmt.Sprintf("Error creating task: %v", err)
+		return
+	}
+	if err := b.reload(); err != nil {
+		b.message = err.Error()
+		return
+	}
+	for row, e := range b.columns[b.col] {
+		if e.ID == t.ID {
+			b.rows[b.col] = row
+		}
+	}
+	b.message = fmt.Sprintf("Task '%s' created.", title)
+}
+
+// moveSelected moves the selected task delta columns to the right, writing its new status.
+func (b *Board) moveSelected(delta int) {
+	e := b.selected()
+	if e == nil {
+		return
+	}
+	target := b.col + delta
+	if target < 0 || target >= len(statuses) {
+		return
+	}
+	if _, err := task.SetTaskStatus(b.cfg, e.Project, e.ID, statuses[target]); err != nil {
+		b.message = fmt.Sprintf("Error moving task: %v", err)
+		return
+	}
+	id := e.ID
+	if err := b.reload(); err != nil {
+		b.message = err.Error()
+		return
+	}
+	b.col = target
+	for row, moved := range b.columns[target] {
+		if moved.ID == id {
+			b.rows[target] = row
+		}
+	}
+}
+
+// editSelected opens the selected note in $EDITOR, leaving the board while it runs.
+func (b *Board) editSelected(restore *func()) error {
+	e := b.selected()
+	if e == nil {
+		return nil
+	}
+	if e.File == "" {
+		b.message = "This task is not stored in a file and cannot be opened in an editor."
+		return nil
+	}
+
+	(*restore)()
+	runErr := utils.RunEditor(e.File)
+
+	newRestore, err := b.enterScreen()
+	if err != nil {
+		*restore = func() {}
+		return err
+	}
+	*restore = newRestore
+	if runErr != nil {
+		b.message = fmt.Sprintf("Editor failed: %v", runErr)
+	}
+	if err := b.reload(); err != nil {
+		b.message = err.Error()
+	}
+	return nil
+}
+
+// togglePomodoro starts a Pomodoro cycle on the selected task, pauses or resumes the
+// running phase, or starts the next phase of a cycle waiting between two.
+func (b *Board) togglePomodoro() {
+	if b.pomodoro != nil {
+		if b.pomodoro.machine.Phase() == pomodoro.PhaseIdle {
+			b.startPhase()
+		} else {
+			b.pomodoro.machine.TogglePause()
+		}
+		return
+	}
+	e := b.selected()
+	if e == nil {
+		return
+	}
+	if e.Status != config.StatusInProgress {
+		if _, err := task.SetTaskStatus(b.cfg, e.Project, e.ID, config.StatusInProgress); err != nil {
+			b.message = fmt.Sprintf("Error starting task: %v", err)
+			return
+		}
+	}
+	b.pomodoro = &pomodoroCycle{entry: *e, machine: pomodoro.NewMachine(pomodoro.SettingsFromConfig(b.cfg), b.clock)}
+	b.startPhase()
+	if err := b.reload(); err != nil {
+		b.message = err.Error()
+	}
+}
+
+// startPhase starts the next phase of the cycle.
+func (b *Board) startPhase() {
+	if b.pomodoro.machine.Advance() == pomodoro.PhaseWork {
+		b.playSound(b.cfg.Sounds.Start)
+	}
+}
+
+// skipPhase ends the running phase early and moves on to the next one.
+func (b *Board) skipPhase() {
+	if b.pomodoro == nil || b.pomodoro.machine.Phase() == pomodoro.PhaseIdle {
+		return
+	}
+	outcome := b.pomodoro.machine.Interrupt()
+	b.message = fmt.Sprintf("%s skipped.", outcome.Phase.Title())
+	b.recordPhase(outcome)
+	b.afterPhase()
+}
+
+// stopPomodoro ends the cycle, recording the phase that was running.
+func (b *Board) stopPomodoro() {
+	if b.pomodoro == nil {
+		return
+	}
+	if b.pomodoro.machine.Phase() != pomodoro.PhaseIdle {
+		b.recordPhase(b.pomodoro.machine.Interrupt())
+	}
+	b.message = fmt.Sprintf("Pomodoro cycle on '%s' stopped.", b.pomodoro.entry.Title)
+	b.pomodoro = nil
+}
+
+// tick completes the running phase once its time is up, and reports the sounds that
+// could not be played.
+func (b *Board) tick() {
+	select {
+	case err := <-b.soundErrs:
+		b.message = fmt.Sprintf("Could not play sound: %v", err)
+	default:
+	}
+	if b.pomodoro == nil || !b.pomodoro.machine.Expired() {
+		return
+	}
+	m := b.pomodoro.machine
+	outcome := m.Complete()
+	if outcome.Phase == pomodoro.PhaseWork {
+		b.message = fmt.Sprintf("Pomodoro on '%s' finished! (%d/%d before a long break)", b.pomodoro.entry.Title, m.CompletedInCycle(), m.Settings().LongBreakInterval)
+		b.playSound(b.cfg.Sounds.Break)
+	} else {
+		b.message = "Break finished!"
+	}
+	b.recordPhase(outcome)
+	b.afterPhase()
+}
+
+// afterPhase starts the next phase when the cycle advances by itself.
+func (b *Board) afterPhase() {
+	if b.pomodoro.machine.Settings().AutoAdvance {
+		b.startPhase()
+	}
+}
+
+// recordPhase logs a finished phase as a session of the cycle's task.
+func (b *Board) recordPhase(outcome pomodoro.Outcome) {
+	err := task.RecordSession(b.cfg, outcome.Session(b.pomodoro.entry.Project, b.pomodoro.entry.ID))
+	if err != nil {
+		b.message = fmt.Sprintf("Could not record %s: %v", outcome.Phase.Label(), err)
+	}
+	if err := b.reload(); err != nil {
+		b.message = err.Error()
+	}
+}
+
+// playSound plays a sound in the background. Its error is reported by the next tick.
+func (b *Board) playSound(path string) {
+	go func() {
+		if err := utils.PlaySound(path); err != nil {
+			select {
+			case b.soundErrs <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// draw renders the whole board.
+func (b *Board) draw() {
+	width, height := utils.TerminalSize()
+	colWidth := width / len(statuses)
+	listHeight := height - 4 // header, separator, status bar and help line
+
+	var lines []string
+	var header strings.Builder
+	for col, status := range statuses {
+		title := fmt.Sprintf(" %s (%d)", strings.ToUpper(status), len(b.columns[col]))
+		if col == b.col {
+			header.WriteString(bold + pad(title, colWidth) + reset)
+		} else {
+			header.WriteString(pad(title, colWidth))
+		}
+	}
+	lines = append(lines, header.String(), strings.Repeat("─", width))
+
+	offsets := make([]int, len(statuses))
+	for col := range statuses {
+		if b.rows[col] >= listHeight {
+			offsets[col] = b.rows[col] - listHeight + 1
+		}
+	}
+	for i := 0; i < listHeight; i++ {
+		var line strings.Builder
+		for col := range statuses {
+			row := offsets[col] + i
+			if row >= len(b.columns[col]) {
+				line.WriteString(strings.Repeat(" ", colWidth))
+				continue
+			}
+			line.WriteString(b.cell(col, row, colWidth))
+		}
+		lines = append(lines, line.String())
+	}
+
+	lines = append(lines, b.statusBar(width), dim+truncate(helpLine, width)+reset)
+	fmt.Print(clearScreen + strings.Join(lines, "\r\n"))
+}
+
+// cell renders one task of the board.
+func (b *Board) cell(col, row, width int) string {
+	e := b.columns[col][row]
+	text := e.Title
+	if e.Issue != 0 {
+		text = fmt.Sprintf("#%d %s", e.Issue, text)
+	}
+	if b.project == "" {
+		text = fmt.Sprintf("[%s] %s", e.Project, text)
+	}
+	if b.pomodoro != nil && b.pomodoro.entry.ID == e.ID {
+		text = "🍅 " + text
+	}
+	text = pad(" "+text, width-1) + " "
+	if col == b.col && row == b.rows[col] {
+		return reverse + text + reset
+	}
+	return text
+}
+
+// statusBar shows the new task prompt, the running Pomodoro or the last message.
+func (b *Board) statusBar(width int) string {
+	switch {
+	case b.input != nil:
+		return bold + truncate(fmt.Sprintf("New task in %s: %s█", statuses[b.col], *b.input), width) + reset
+	case b.pomodoro != nil:
+		status := b.pomodoroStatus()
+		if b.message != "" {
+			status += "  │ " + b.message
+		}
+		return reverse + pad(status, width) + reset
+	default:
+		return truncate(b.message, width)
+	}
+}
+
+// pomodoroStatus describes the phase of the running cycle.
+func (b *Board) pomodoroStatus() string {
+	m := b.pomodoro.machine
+	if m.Phase() == pomodoro.PhaseIdle {
+		return fmt.Sprintf("🍅 %s  press p to start the %s", b.pomodoro.entry.Title, m.Next().Label())
+	}
+	remaining := m.Remaining().Round(time.Second)
+	status := fmt.Sprintf("🍅 %02d:%02d  %s", int(remaining.Minutes()), int(remaining.Seconds())%60, b.pomodoro.entry.Title)
+	if m.Phase() != pomodoro.PhaseWork {
+		status = fmt.Sprintf("☕ %02d:%02d  %s", int(remaining.Minutes()), int(remaining.Seconds())%60, m.Phase().Title())
+	}
+	if m.Paused() {
+		status += " (paused)"
+	}
+	return status
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// pad truncates or right-pads s with spaces to exactly width runes.
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	if n := len([]rune(s)); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
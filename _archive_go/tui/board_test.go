@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"tasky/config"
+	"tasky/pomodoro"
+	"tasky/session"
+	"tasky/task"
+	"tasky/utils"
+)
+
+// fakeClock is a pomodoro.Clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestBoardPomodoroCycle(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	utils.SetStoreBackend(utils.NewMemoryBackend())
+	t.Cleanup(func() { utils.SetStoreBackend(nil) })
+
+	var cfg config.Config
+	cfg.General.Project = "proj"
+	cfg.Pomodoro.SessionLog = filepath.Join(t.TempDir(), "sessions.ndjson")
+	cfg.Pomodoro.LongBreakInterval = 2
+	note := config.Task{Frontmatter: config.Frontmatter{Title: "Write docs", Status: config.StatusTodo}}
+	if _, err := task.CreateTaskNote(cfg, "proj", &note, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)}
+	b := newBoard(cfg, "proj", clock)
+	if err := b.reload(); err != nil {
+		t.Fatal(err)
+	}
+	press := func(key string) {
+		t.Helper()
+		if _, err := b.handleKey(key, nil); err != nil {
+			t.Fatalf("key %q: %v", key, err)
+		}
+	}
+	wait := func(d time.Duration) {
+		clock.advance(d)
+		b.tick()
+	}
+	phase := func() pomodoro.Phase {
+		if b.pomodoro == nil {
+			return ""
+		}
+		return b.pomodoro.machine.Phase()
+	}
+
+	press("p")
+	if phase() != pomodoro.PhaseWork {
+		t.Fatalf("p started %q, want a Pomodoro", phase())
+	}
+	if e := b.selected(); e == nil || e.ID != note.ID || e.Status != config.StatusInProgress {
+		t.Errorf("the task was not started: %+v", e)
+	}
+	wait(24 * time.Minute)
+	if phase() != pomodoro.PhaseWork {
+		t.Fatalf("Pomodoro ended early: %q", phase())
+	}
+	wait(time.Minute)
+	if phase() != pomodoro.PhaseIdle || b.pomodoro.machine.Next() != pomodoro.PhaseShortBreak {
+		t.Fatalf("after a Pomodoro: %q, next %q", phase(), b.pomodoro.machine.Next())
+	}
+
+	// A paused break does not run out.
+	press("p")
+	press("p")
+	wait(10 * time.Minute)
+	if phase() != pomodoro.PhaseShortBreak {
+		t.Fatalf("paused break: %q", phase())
+	}
+	press("p")
+	wait(5 * time.Minute)
+	if phase() != pomodoro.PhaseIdle {
+		t.Fatalf("break did not end: %q", phase())
+	}
+
+	// With AutoAdvance, each phase starts as soon as the previous one ends.
+	b.pomodoro.machine = pomodoro.NewMachine(pomodoro.Settings{Work: 25 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, LongBreakInterval: 1, AutoAdvance: true}, clock)
+	press("p")
+	press("+")
+	wait(26 * time.Minute)
+	if phase() != pomodoro.PhaseLongBreak {
+		t.Fatalf("after an extended Pomodoro: %q, want a long break", phase())
+	}
+	press("s")
+	if phase() != pomodoro.PhaseWork {
+		t.Fatalf("after skipping the break: %q", phase())
+	}
+	wait(10 * time.Minute)
+	press("a")
+	if b.pomodoro != nil {
+		t.Fatal("a did not stop the cycle")
+	}
+
+	sessions, err := session.Read(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []session.Kind
+	for _, s := range sessions {
+		kinds = append(kinds, s.Kind)
+	}
+	want := []session.Kind{session.KindWork, session.KindShortBreak, session.KindWork, session.KindLongBreak, session.KindWork}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("logged %v, want %v", kinds, want)
+	}
+	if s := sessions[1]; s.Elapsed != 5*time.Minute || !s.Completed {
+		t.Errorf("paused break logged as %+v", s)
+	}
+	if s := sessions[4]; s.Elapsed != 10*time.Minute || s.Completed {
+		t.Errorf("stopped Pomodoro logged as %+v", s)
+	}
+	e, err := task.NewService(cfg).Find("proj", note.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.PomodoroCount != 2 || e.Duration != 61 {
+		t.Errorf("task counts %d Pomodoros and %d minutes, want 2 and 61", e.PomodoroCount, e.Duration)
+	}
+}
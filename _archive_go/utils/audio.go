@@ -2,7 +2,6 @@ package utils
 
 import (
 	"fmt"
-	"os/exec"
 )
 
 // PlaySound plays a WAV file using the 'aplay' command.
@@ -12,9 +11,7 @@ func PlaySound(filePath string) error {
 		return nil // No sound file specified
 	}
 
-	cmd := exec.Command("aplay", filePath)
-	err := cmd.Run()
-	if err != nil {
+	if _, err := RunCmd("aplay", filePath); err != nil {
 		return fmt.Errorf("error playing sound %s: %w", filePath, err)
 	}
 	return nil
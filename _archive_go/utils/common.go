@@ -1,30 +1,12 @@
 package utils
 
 import (
-	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
-// RunCmd executes a shell command and returns its stdout. If the command fails, it returns an error.
-// This comment is added to force a recompile.
-func RunCmd(name string, arg ...string) (string, error) {
-	cmd := exec.Command(name, arg...)
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("command failed: %s %s\nStdout: %s\nStderr: %s\nError: %w", name, strings.Join(arg, " "), out.String(), stderr.String(), err)
-	}
-	return strings.TrimSpace(out.String()), nil
-}
-
 // LogWriter is an io.Writer that logs output to stdout or stderr.
 type LogWriter struct {
 	isStderr bool
@@ -61,6 +43,19 @@ func ListMarkdownFiles(root string) ([]string, error) {
 
 // SimulatePauseKey simulates a media play-pause action using playerctl (Linux only)
 func SimulatePauseKey() error {
-	cmd := exec.Command("playerctl", "play-pause")
-	return cmd.Run()
+	_, err := RunCmd("playerctl", "play-pause")
+	return err
+}
+
+// ShellQuote joins args into a command line a POSIX shell would split back into args.
+func ShellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@%+,") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
 }
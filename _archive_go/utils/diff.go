@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines LineDiff shows around a change.
+const diffContext = 2
+
+// LineDiff returns the changes from before to after as a unified diff of their lines,
+// headed by name, or "" if they are equal.
+func LineDiff(name, before, after string) string {
+	if before == after {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", name, name)
+	skipped := false
+	for k, line := range lines {
+		if !nearChange(lines, k) {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("...\n")
+			skipped = false
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// nearChange reports whether lines[k] is a change or within diffContext lines of one.
+func nearChange(lines []string, k int) bool {
+	for i := max(0, k-diffContext); i <= min(len(lines)-1, k+diffContext); i++ {
+		if lines[i][0] != ' ' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RunEditor opens path in $EDITOR, or vi, attached to the terminal, and waits for it to
+// exit.
+func RunEditor(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// WriteFileAtomic replaces path with content so that readers, and the file after a crash,
+// only ever see the old or the new content: it writes a temporary file next to path,
+// flushes it to disk and renames it over path. An existing file keeps its permissions.
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	cleanup := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return cleanup(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// Persist the rename itself.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// lockTimeout is how long LockFile waits for another process to release a lock.
+const lockTimeout = 10 * time.Second
+
+// heldLock is a lock file this process holds, see LockFile.
+type heldLock struct {
+	file  *os.File
+	count int
+}
+
+var (
+	heldLocksMu sync.Mutex
+	heldLocks   = make(map[string]*heldLock)
+)
+
+// LockFile takes an exclusive advisory lock (flock) on path, creating the file if needed,
+// and returns the function releasing it. The lock only excludes other processes that lock
+// the same path; within a process it is reentrant, so a caller holding it can call code
+// that takes it again. It gives up after lockTimeout.
+func LockFile(path string) (func(), error) {
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+
+	held, ok := heldLocks[path]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		for deadline := time.Now().Add(lockTimeout); ; {
+			err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, syscall.EWOULDBLOCK) || time.Now().After(deadline) {
+				f.Close()
+				return nil, fmt.Errorf("could not lock %s: %w", path, err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		held = &heldLock{file: f}
+		heldLocks[path] = held
+	}
+	held.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			heldLocksMu.Lock()
+			defer heldLocksMu.Unlock()
+			held.count--
+			if held.count == 0 {
+				syscall.Flock(int(held.file.Fd()), syscall.LOCK_UN)
+				held.file.Close()
+				delete(heldLocks, path)
+			}
+		})
+	}, nil
+}
+
+// lockPath returns a lock file in the user cache directory for the resource identified
+// by key, so that no lock file is left in the vault.
+func lockPath(key string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cacheDir, "tasky", "locks", hex.EncodeToString(sum[:8])+".lock"), nil
+}
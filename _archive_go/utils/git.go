@@ -2,51 +2,38 @@ package utils
 
 import (
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+
+	"tasky/config"
 )
 
 // IsGitRepository checks if the current directory is a Git repository.
 func IsGitRepository() bool {
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
-	err := cmd.Run()
+	_, err := RunCmd("git", "rev-parse", "--is-inside-work-tree")
 	return err == nil
 }
 
-// HasGitHubRemote checks if the current Git repository has a remote pointing to GitHub.
-func HasGitHubRemote() bool {
-	cmd := exec.Command("git", "remote", "-v")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
+// GetProjectName returns the name of the project of the current directory: the one set in
+// general.project, else the name of the repository's remote as general.project_naming
+// says, else the name of the repository's directory or of the current directory.
+func GetProjectName(cfg config.Config) string {
+	if cfg.General.Project != "" {
+		return cfg.General.Project
 	}
-	return strings.Contains(string(output), "github.com")
-}
-
-// GetProjectName returns the Git repository name or the current directory name.
-func GetProjectName() string {
 	if IsGitRepository() {
-		// Try to get the repository name from the remote URL
-		cmd := exec.Command("git", "config", "--get", "remote.origin.url")
-		output, err := cmd.Output()
-		if err == nil {
-			url := strings.TrimSpace(string(output))
-			if strings.Contains(url, "github.com") {
-				parts := strings.Split(url, ":")
-				if len(parts) > 1 {
-					repoPath := strings.TrimSuffix(parts[1], ".git")
-					return filepath.Base(repoPath)
-				}
+		if remote, err := RepositoryRemote(); err == nil {
+			if cfg.General.ProjectNaming == config.ProjectNamingOwnerRepo && remote.Owner != "" {
+				return remote.Owner + "/" + remote.Repo
 			}
+			return remote.Repo
 		}
 
 		// Fallback to the top-level directory name of the Git repository
-		cmd = exec.Command("git", "rev-parse", "--show-toplevel")
-		output, err = cmd.Output()
-		if err == nil {
-			return filepath.Base(strings.TrimSpace(string(output)))
+		if toplevel, err := RunCmd("git", "rev-parse", "--show-toplevel"); err == nil {
+			return filepath.Base(toplevel)
 		}
 	}
 
@@ -60,20 +47,56 @@ func GetProjectName() string {
 
 // GetCurrentBranchName returns the current git branch name, or an error if not in a git repo.
 func GetCurrentBranchName() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(output)), nil
+	return RunCmd("git", "rev-parse", "--abbrev-ref", "HEAD")
 }
 
-// ExtractIssueNumberFromBranch extrait le numéro d'issue du nom de branche (ex: 123-feature-x -> 123).
-func ExtractIssueNumberFromBranch(branchName string) string {
-	re := regexp.MustCompile(`^([0-9]+)-`)
-	matches := re.FindStringSubmatch(branchName)
-	if len(matches) > 1 {
-		return matches[1]
+// ExtractIssueNumberFromBranch returns the issue number in a branch name, found by the
+// first matching pattern of branch.patterns, or "" (e.g. 123-feature-x -> 123).
+func ExtractIssueNumberFromBranch(cfg config.Config, branchName string) string {
+	for _, pattern := range cfg.Branch.IssuePatterns() {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		if m := re.FindStringSubmatch(branchName); m != nil && m[re.SubexpIndex("issue")] != "" {
+			return m[re.SubexpIndex("issue")]
+		}
 	}
 	return ""
 }
+
+// repeatedSeparators matches a run of separators in a branch name.
+var repeatedSeparators = regexp.MustCompile(`([-_/])[-_/]+`)
+
+// BranchName names the branch of a task after branch.template. Without issue, {issue}
+// is the task ID; an empty branchType is branch.default_type.
+func BranchName(cfg config.Config, branchType string, issue int, id, title string) string {
+	if branchType == "" {
+		branchType = cfg.Branch.Type()
+	}
+	issueText := id
+	if issue != 0 {
+		issueText = strconv.Itoa(issue)
+	}
+	name := strings.NewReplacer("{type}", branchType, "{issue}", issueText, "{slug}", Slugify(title), "{id}", id).
+		Replace(cfg.Branch.NameTemplate())
+	// Drop the separators left around empty placeholders.
+	name = repeatedSeparators.ReplaceAllString(name, "$1")
+	return strings.Trim(name, "-_/")
+}
+
+// BranchExists reports whether the repository has a local branch named branch.
+func BranchExists(branch string) bool {
+	_, err := RunCmd("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// CheckoutBranch checks out branch, creating it from the current commit if needed.
+func CheckoutBranch(branch string) error {
+	if BranchExists(branch) {
+		_, err := RunCmd("git", "checkout", branch)
+		return err
+	}
+	_, err := RunCmd("git", "checkout", "-b", branch)
+	return err
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"testing"
+
+	"tasky/config"
+)
+
+func TestExtractIssueNumberFromBranch(t *testing.T) {
+	var team config.Config
+	team.Branch.Patterns = []string{
+		`^[a-z]+/[A-Z]+-(?P<issue>[0-9]+)-`,
+		`^[a-z]+/(?P<issue>[0-9]+)_`,
+		`^(?P<issue>[0-9]+)/`,
+	}
+	tests := []struct {
+		cfg    config.Config
+		branch string
+		want   string
+	}{
+		{config.Config{}, "123-feature-x", "123"},
+		{config.Config{}, "feature/123-x", ""},
+		{config.Config{}, "main", ""},
+		{team, "feature/PROJ-123-foo", "123"},
+		{team, "fix/123_bar", "123"},
+		{team, "123/short-name", "123"},
+		{team, "123-feature-x", ""}, // the default pattern is replaced
+		{team, "feature/PROJ-foo", ""},
+		{team, "fix/bar_123", ""},
+		{team, "release/2024", ""},
+	}
+	for _, tt := range tests {
+		if got := ExtractIssueNumberFromBranch(tt.cfg, tt.branch); got != tt.want {
+			t.Errorf("ExtractIssueNumberFromBranch(%v, %q) = %q, want %q", tt.cfg.Branch.Patterns, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	tests := []struct {
+		template, defaultType string
+		branchType            string
+		issue                 int
+		title                 string
+		want                  string
+	}{
+		{"", "", "", 123, "Fix the login", "123-fix-the-login"},
+		{"", "", "", 0, "Fix the login", "abc123-fix-the-login"},
+		{"{type}/{issue}-{slug}", "", "", 123, "Fix the login", "feature/123-fix-the-login"},
+		{"{type}/{issue}-{slug}", "chore", "", 123, "Fix the login", "chore/123-fix-the-login"},
+		{"{type}/{issue}-{slug}", "chore", "fix", 123, "Fix the login", "fix/123-fix-the-login"},
+		{"{type}/{issue}_{slug}", "", "fix", 7, "Bar", "fix/7_bar"},
+		{"{issue}/{slug}", "", "", 123, "Short name", "123/short-name"},
+		{"{type}/{issue}-{slug}", "", "", 123, "!!!", "feature/123"},
+		{"{type}/{slug}-{id}", "", "", 0, "", "feature/abc123"},
+	}
+	for _, tt := range tests {
+		var cfg config.Config
+		cfg.Branch.Template, cfg.Branch.DefaultType = tt.template, tt.defaultType
+		if got := BranchName(cfg, tt.branchType, tt.issue, "abc123", tt.title); got != tt.want {
+			t.Errorf("BranchName(%q, %q, %d, %q) = %q, want %q", tt.template, tt.branchType, tt.issue, tt.title, got, tt.want)
+		}
+	}
+}
+
+// Branches named after the template are found again by a pattern matching it.
+func TestBranchNameRoundTrip(t *testing.T) {
+	var cfg config.Config
+	cfg.Branch.Template = "{type}/{issue}-{slug}"
+	cfg.Branch.Patterns = []string{`^[a-z]+/(?P<issue>[0-9]+)-`}
+	branch := BranchName(cfg, "", 42, "abc123", "Add search")
+	if got := ExtractIssueNumberFromBranch(cfg, branch); got != "42" {
+		t.Errorf("issue of %s: got %q, want 42", branch, got)
+	}
+}
@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"tasky/config"
+)
+
+// TaskyDir returns the directory of the vault holding the notes of projectName, as
+// storage.layout places it, without creating it.
+func TaskyDir(cfg config.Config, projectName string) string {
+	return config.LayoutDir(cfg.General.VaultPath, cfg.Storage.LayoutTemplate(), projectName)
+}
+
+// LayoutProjects returns the projects with a notes directory in vault according to
+// layout. Projects named "owner/repo" are found one level deeper than the others. When
+// the layout ends with the project's directory, the folders of the vault are not all
+// projects: only the directories holding a task note in the given format are, and a
+// directory holding none itself is taken for an owner.
+func LayoutProjects(vault, layout string, format config.NoteFormat) ([]string, error) {
+	prefix, suffix := config.SplitLayout(layout)
+	root := filepath.Join(vault, filepath.FromSlash(prefix))
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// An owner's repositories may keep their notes in subdirectories only.
+	isProject := func(dir string, owned bool) bool {
+		if suffix != "" {
+			return isDir(dir + filepath.FromSlash(suffix))
+		}
+		return hasTaskNotes(dir, format, owned)
+	}
+	var projects []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if isProject(dir, false) {
+			projects = append(projects, entry.Name())
+			continue
+		}
+		subEntries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, sub := range subEntries {
+			if sub.IsDir() && !strings.HasPrefix(sub.Name(), ".") && isProject(filepath.Join(dir, sub.Name()), true) {
+				projects = append(projects, entry.Name()+"/"+sub.Name())
+			}
+		}
+	}
+	return projects, nil
+}
+
+// LayoutMove moves one file or directory of a project's notes directory to its place in
+// another layout.
+type LayoutMove struct {
+	Project  string
+	From, To string
+}
+
+// PlanLayoutMigration returns the moves bringing the notes of every project of vault from
+// one layout to another. Only the projects holding a task note in the given format are
+// moved. When from ends with the project's directory, as {project}, that directory is
+// also the user's: only the task notes are moved out of it, and the other notes of the
+// vault are left alone. It fails without planning anything if a destination exists.
+func PlanLayoutMigration(vault, from, to string, format config.NoteFormat) ([]LayoutMove, error) {
+	if err := config.ValidateLayout(from); err != nil {
+		return nil, fmt.Errorf("layout '%s': %w", from, err)
+	}
+	if err := config.ValidateLayout(to); err != nil {
+		return nil, fmt.Errorf("layout '%s': %w", to, err)
+	}
+	if from == to {
+		return nil, nil
+	}
+	projects, err := LayoutProjects(vault, from, format)
+	if err != nil {
+		return nil, err
+	}
+	_, suffix := config.SplitLayout(from)
+	var moves []LayoutMove
+	for _, projectName := range projects {
+		src := config.LayoutDir(vault, from, projectName)
+		dst := config.LayoutDir(vault, to, projectName)
+		var names []string
+		switch {
+		case suffix == "":
+			names, err = taskNoteNames(src, dst, format)
+		case hasTaskNotes(src, format, true):
+			names, err = entryNames(src, dst)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			move := LayoutMove{Project: projectName, From: filepath.Join(src, name), To: filepath.Join(dst, name)}
+			if _, err := os.Lstat(move.To); err == nil {
+				return nil, fmt.Errorf("cannot move %s: %s already exists", move.From, move.To)
+			}
+			moves = append(moves, move)
+		}
+	}
+	return moves, nil
+}
+
+// entryNames returns the names of the entries of src, but the one dst is in.
+func entryNames(src, dst string) ([]string, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if isWithin(dst, filepath.Join(src, entry.Name())) {
+			// The destination is inside this entry, as "Tasky" moving from
+			// {project}/Tasky to Tasky/{project}: it is not a note.
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// taskNoteNames returns the paths, relative to src, of the task notes in src and its
+// subdirectories, but those already in dst.
+func taskNoteNames(src, dst string, format config.NoteFormat) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != src && (strings.HasPrefix(entry.Name(), ".") || isWithin(path, dst)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(entry.Name(), ".md") && isTaskNote(path, format) {
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, rel)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// isWithin reports whether path is dir or inside it.
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path+string(filepath.Separator), dir+string(filepath.Separator))
+}
+
+// hasTaskNotes reports whether dir holds a task note, or one of its subdirectories too
+// when recursive is set.
+func hasTaskNotes(dir string, format config.NoteFormat, recursive bool) bool {
+	found := errors.New("found")
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() && path != dir && (!recursive || strings.HasPrefix(entry.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".md") && isTaskNote(path, format) {
+			return found
+		}
+		return nil
+	})
+	return err == found
+}
+
+// isTaskNote reports whether the note at path is a task rather than another note of the
+// vault: its frontmatter holds a title and a known status, under the keys of format.
+func isTaskNote(path string, format config.NoteFormat) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if lines[0] != "---" {
+		return false
+	}
+	end := 1
+	for end < len(lines) && lines[end] != "---" && lines[end] != "..." {
+		end++
+	}
+	if end == len(lines) {
+		return false
+	}
+	var fm map[string]any
+	if yaml.Unmarshal([]byte(strings.Join(lines[1:end], "\n")), &fm) != nil {
+		return false
+	}
+	title, _ := fm[format.Key("title")].(string)
+	status, _ := fm[format.Key("status")].(string)
+	if strings.TrimSpace(title) == "" {
+		return false
+	}
+	normalized := strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(status))
+	for _, known := range []string{config.StatusTodo, config.StatusInProgress, config.StatusDone} {
+		if normalized == known || strings.EqualFold(status, format.StatusValue(known)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyLayoutMigration performs the moves planned by PlanLayoutMigration, then removes
+// the directories they emptied. It holds the store lock of every project moved, in both
+// layouts, and fails without moving anything if the notes changed since the moves were
+// planned. If a move fails, the ones already done are undone and the error is returned.
+func ApplyLayoutMigration(vault, from, to string, format config.NoteFormat, moves []LayoutMove) error {
+	unlock, err := lockLayoutProjects(vault, from, to, moves)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	planned, err := PlanLayoutMigration(vault, from, to, format)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(planned, moves) {
+		return fmt.Errorf("the notes changed since the moves were planned; nothing was moved")
+	}
+
+	for i, move := range moves {
+		err := os.MkdirAll(filepath.Dir(move.To), 0755)
+		if err == nil {
+			err = os.Rename(move.From, move.To)
+		}
+		if err != nil {
+			if rollbackErr := undoLayoutMoves(vault, moves[:i+1]); rollbackErr != nil {
+				return fmt.Errorf("moving %s: %w; rolling back: %v", move.From, err, rollbackErr)
+			}
+			return fmt.Errorf("moving %s: %w (the moves done were rolled back)", move.From, err)
+		}
+	}
+	for _, move := range moves {
+		removeEmptyDirs(vault, filepath.Dir(move.From))
+	}
+	return nil
+}
+
+// lockLayoutProjects takes the store lock of the projects of moves in both layouts, so
+// that no tasky process writes a note being moved. The locks are taken in order, and the
+// returned function releases them all.
+func lockLayoutProjects(vault, from, to string, moves []LayoutMove) (func(), error) {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, move := range moves {
+		for _, dir := range []string{config.LayoutDir(vault, from, move.Project), config.LayoutDir(vault, to, move.Project)} {
+			if !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	sort.Strings(dirs)
+	var unlocks []func()
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, dir := range dirs {
+		unlock, err := (&MarkdownStore{Dir: dir}).Lock()
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+	return unlockAll, nil
+}
+
+// undoLayoutMoves moves back the moves done, in reverse order, and removes the
+// directories created for them. Moves not done are skipped.
+func undoLayoutMoves(vault string, moves []LayoutMove) error {
+	var errs []error
+	for i := len(moves) - 1; i >= 0; i-- {
+		if _, err := os.Lstat(moves[i].To); err == nil {
+			if err := os.Rename(moves[i].To, moves[i].From); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		removeEmptyDirs(vault, filepath.Dir(moves[i].To))
+	}
+	return errors.Join(errs...)
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty directories, up to
+// vault.
+func removeEmptyDirs(vault, dir string) {
+	for dir != vault && strings.HasPrefix(dir, vault+string(filepath.Separator)) {
+		if info, err := os.Lstat(dir); err != nil || !info.IsDir() || os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
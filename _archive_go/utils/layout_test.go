@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tasky/config"
+)
+
+func TestLayoutMigration(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir()) // store locks
+	vault := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(vault, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("proj/fix-login.md", "---\nid: abc123\ntitle: Fix login\nstatus: todo\n---\n")
+	write("proj/meeting.md", "# Notes of the project that are not tasks\n")
+	write("owner/repo/archive/old.md", "---\r\ntitle: Old\r\nstatus: In_Progress\r\n...\r\nbody\r\n")
+	write("Journal/monday.md", "---\nid: 2024-01-01\ntags: [daily]\n---\nNo task here.\n")
+	write("Templates/task.md", "---\ntitle: \"\"\nstatus: todo\n---\n")
+	write("Ideas/draft.md", "# Not a task\n")
+
+	projects, err := LayoutProjects(vault, "{project}", config.NoteFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 || projects[0] != "owner/repo" || projects[1] != "proj" {
+		t.Errorf("LayoutProjects() = %q, want the folders holding tasks", projects)
+	}
+
+	moves, err := PlanLayoutMigration(vault, "{project}", "Tasky/{project}", config.NoteFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moved []string
+	for _, move := range moves {
+		rel, _ := filepath.Rel(vault, move.From)
+		moved = append(moved, filepath.ToSlash(rel))
+	}
+	if len(moved) != 2 || moved[0] != "owner/repo/archive/old.md" || moved[1] != "proj/fix-login.md" {
+		t.Fatalf("planned moves of %q, want only the task notes", moved)
+	}
+
+	// A note written after planning is not left behind.
+	write("proj/new.md", "---\nid: ghi789\ntitle: New\nstatus: done\n---\n")
+	if err := ApplyLayoutMigration(vault, "{project}", "Tasky/{project}", config.NoteFormat{}, moves); err == nil {
+		t.Fatal("ApplyLayoutMigration moved notes planned before a change")
+	}
+	if _, err := os.Stat(filepath.Join(vault, "proj", "fix-login.md")); err != nil {
+		t.Fatalf("a note was moved: %v", err)
+	}
+
+	moves, err = PlanLayoutMigration(vault, "{project}", "Tasky/{project}", config.NoteFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyLayoutMigration(vault, "{project}", "Tasky/{project}", config.NoteFormat{}, moves); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"Tasky/proj/fix-login.md", "Tasky/proj/new.md", "Tasky/owner/repo/archive/old.md", "proj/meeting.md", "Journal/monday.md", "Templates/task.md", "Ideas/draft.md"} {
+		if _, err := os.Stat(filepath.Join(vault, filepath.FromSlash(path))); err != nil {
+			t.Errorf("after migrating: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(vault, "owner")); !os.IsNotExist(err) {
+		t.Errorf("the emptied directories were left: %v", err)
+	}
+}
+
+func TestLayoutMigrationIntoProject(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	vault := t.TempDir()
+	for path, content := range map[string]string{
+		"proj/fix-login.md":        "---\ntitle: Fix login\nstatus: done\n---\n",
+		"proj/meeting.md":          "# Not a task\n",
+		"proj/attachments/map.png": "png",
+		"Journal/monday.md":        "# Not a task\n",
+	} {
+		path = filepath.Join(vault, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moves, err := PlanLayoutMigration(vault, "{project}", "{project}/Tasky", config.NoteFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].From != filepath.Join(vault, "proj", "fix-login.md") || moves[0].To != filepath.Join(vault, "proj", "Tasky", "fix-login.md") {
+		t.Fatalf("planned %+v, want only the task note moved", moves)
+	}
+	if err := ApplyLayoutMigration(vault, "{project}", "{project}/Tasky", config.NoteFormat{}, moves); err != nil {
+		t.Fatal(err)
+	}
+	// Back to {project}: everything in the Tasky directory is tasky's.
+	moves, err = PlanLayoutMigration(vault, "{project}/Tasky", "{project}", config.NoteFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].To != filepath.Join(vault, "proj", "fix-login.md") {
+		t.Fatalf("planned %+v, want the task note moved back", moves)
+	}
+}
+
+func TestIsTaskNote(t *testing.T) {
+	format := config.NoteFormat{Keys: map[string]string{"status": "state"}, Status: config.StatusValues{InProgress: "doing"}}
+	tests := []struct {
+		note string
+		want bool
+	}{
+		{"---\ntitle: Fix login\nstate: todo\n---\n", true},
+		{"---\ntitle: Fix login\nstate: doing\n---\n", true},
+		{"---\ntitle: Fix login\nstate: in-progress\n...\n", true},
+		{"---\ntitle: Fix login\nstatus: todo\n---\n", false},
+		{"---\ntitle: Meeting\nstate: scheduled\n---\n", false},
+		{"---\nid: abc123\nstate: todo\n---\n", false},
+		{"---\ntitle: Fix login\nstate: todo\n", false},
+		{"title: Fix login\nstate: todo\n", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "note.md")
+		if err := os.WriteFile(path, []byte(tt.note), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := isTaskNote(path, format); got != tt.want {
+			t.Errorf("isTaskNote(%q) = %v, want %v", tt.note, got, tt.want)
+		}
+	}
+}
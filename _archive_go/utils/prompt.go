@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// interactive is false in non-interactive mode, see SetInteractive.
+var interactive = true
+
+// stdin is shared by every prompt, so that answers piped to tasky are not swallowed by
+// a prompt reading ahead.
+var stdin = bufio.NewReader(os.Stdin)
+
+// SetInteractive enables or disables prompts. Without them, as in scripts and CI, Ask
+// returns the default answer and Confirm answers no, so that nothing outward-facing or
+// destructive happens without being asked for.
+func SetInteractive(enabled bool) {
+	interactive = enabled
+}
+
+// Interactive reports whether tasky may prompt.
+func Interactive() bool {
+	return interactive
+}
+
+// Ask prints question and returns the answer, trimmed. In non-interactive mode it
+// returns "" without asking, which callers take as the default answer.
+func Ask(question string) string {
+	if !interactive {
+		return ""
+	}
+	fmt.Print(question)
+	answer, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// Confirm asks a question answered by yes or no, yes being the default. In
+// non-interactive mode it returns false without asking.
+func Confirm(question string) bool {
+	if !interactive {
+		return false
+	}
+	answer := strings.ToLower(Ask(question))
+	return answer == "y" || answer == ""
+}
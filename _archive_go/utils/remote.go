@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Remote is a Git remote, parsed from its URL.
+type Remote struct {
+	Host string
+	// Owner is the user, organization or group owning the repository. GitLab subgroups
+	// are kept, as in "group/subgroup".
+	Owner string
+	Repo  string
+}
+
+// ParseRemoteURL parses the URL of a Git remote in the forms git accepts:
+// "https://host/owner/repo.git", "ssh://user@host:port/owner/repo", "git://host/owner/repo",
+// the scp-like "user@host:owner/repo.git" and local paths, which have no host. As for git,
+// a single letter before the colon is a Windows drive, as in C:\src\repo, not a host.
+func ParseRemoteURL(rawURL string) (Remote, error) {
+	s := strings.TrimSpace(rawURL)
+	var host, path string
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return Remote{}, fmt.Errorf("invalid remote URL '%s': %w", rawURL, err)
+		}
+		if u.Scheme != "file" {
+			host = u.Hostname()
+		}
+		path = u.Path
+	} else if colon := strings.Index(s, ":"); colon > 0 && !strings.Contains(s[:colon], "/") && !hasDrivePrefix(s) {
+		// scp-like syntax; a colon after a slash belongs to a local path.
+		host, path = s[:colon], s[colon+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		path = s
+	}
+
+	if host == "" {
+		path = strings.TrimLeft(strings.ReplaceAll(path, "\\", "/"), "/")
+		if hasDrivePrefix(path) {
+			path = path[2:]
+		}
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	remote := Remote{Host: strings.ToLower(host), Repo: parts[len(parts)-1]}
+	if remote.Repo == "" {
+		return Remote{}, fmt.Errorf("no repository in remote URL '%s'", rawURL)
+	}
+	if owners := parts[:len(parts)-1]; host != "" {
+		remote.Owner = strings.Join(owners, "/")
+	} else if len(owners) > 0 && owners[len(owners)-1] != ".." && owners[len(owners)-1] != "." {
+		// The directory holding a local repository is the closest thing to an owner.
+		remote.Owner = owners[len(owners)-1]
+	}
+	return remote, nil
+}
+
+// hasDrivePrefix reports whether path starts with a Windows drive, such as "C:".
+func hasDrivePrefix(path string) bool {
+	return len(path) >= 2 && path[1] == ':' && ('a' <= path[0] && path[0] <= 'z' || 'A' <= path[0] && path[0] <= 'Z')
+}
+
+// RepositoryRemote returns the remote of the repository of the current directory: origin,
+// or else its first remote.
+func RepositoryRemote() (Remote, error) {
+	output, err := RunCmd("git", "remote")
+	if err != nil {
+		return Remote{}, err
+	}
+	names := strings.Fields(output)
+	if len(names) == 0 {
+		return Remote{}, errors.New("the repository has no remote")
+	}
+	name := names[0]
+	if slices.Contains(names, "origin") {
+		name = "origin"
+	}
+	rawURL, err := RunCmd("git", "remote", "get-url", name)
+	if err != nil {
+		return Remote{}, err
+	}
+	return ParseRemoteURL(rawURL)
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Expect is a command expected by a Replay, with what it prints or how it fails.
+type Expect struct {
+	// Command is the expected command line, as ShellQuote writes it, such as
+	// "git checkout -b 12-fix".
+	Command string
+	Output  string
+	Err     error
+}
+
+// Replay is a Runner playing back a script instead of running commands, so that tasky
+// runs offline, without a repository or a forge client. Every command run must be the
+// next one of the script, which then answers it; any other command fails. Install it
+// with SetRunner, then check it with Verify.
+type Replay struct {
+	// Calls records every command run, expected or not.
+	Calls []string
+
+	mu     sync.Mutex
+	script []Expect
+	next   int
+	errs   []error
+}
+
+// NewReplay returns a Replay expecting the commands of script, in order.
+func NewReplay(script ...Expect) *Replay {
+	return &Replay{script: script}
+}
+
+func (r *Replay) Run(name string, args ...string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	command := ShellQuote(append([]string{name}, args...))
+	r.Calls = append(r.Calls, command)
+	if r.next == len(r.script) {
+		err := fmt.Errorf("unexpected command %q after the end of the script", command)
+		r.errs = append(r.errs, err)
+		return "", err
+	}
+	expect := r.script[r.next]
+	if command != expect.Command {
+		err := fmt.Errorf("unexpected command %q, expected %q", command, expect.Command)
+		r.errs = append(r.errs, err)
+		return "", err
+	}
+	r.next++
+	return expect.Output, expect.Err
+}
+
+// Verify returns an error listing the unexpected commands run and the expected commands
+// not run, or nil if the script was played exactly.
+func (r *Replay) Verify() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	errs := r.errs
+	if r.next < len(r.script) {
+		var missing []string
+		for _, expect := range r.script[r.next:] {
+			missing = append(missing, expect.Command)
+		}
+		errs = append(errs, fmt.Errorf("commands not run: %s", strings.Join(missing, "; ")))
+	}
+	return errors.Join(errs...)
+}
+
+// Recorder is a Runner recording the commands run by another Runner with their results,
+// as a script for a Replay.
+type Recorder struct {
+	Runner Runner
+	Script []Expect
+
+	mu sync.Mutex
+}
+
+// NewRecorder returns a Recorder running commands with r.
+func NewRecorder(r Runner) *Recorder {
+	return &Recorder{Runner: r}
+}
+
+func (r *Recorder) Run(name string, args ...string) (string, error) {
+	output, err := r.Runner.Run(name, args...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Script = append(r.Script, Expect{Command: ShellQuote(append([]string{name}, args...)), Output: output, Err: err})
+	return output, err
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner runs the external commands of tasky, such as git, gh, aplay and playerctl.
+// Commands attached to the terminal, such as stty or an editor, do not go through it.
+type Runner interface {
+	// Run runs a command and returns its standard output, trimmed. A failing command
+	// returns an error carrying its output.
+	Run(name string, args ...string) (string, error)
+}
+
+// ExecRunner is the Runner running commands with os/exec.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("command failed: %s %s\nStdout: %s\nStderr: %s\nError: %w", name, strings.Join(args, " "), out.String(), stderr.String(), err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// runner runs every command of RunCmd, see SetRunner.
+var runner Runner = ExecRunner{}
+
+// SetRunner makes RunCmd run commands with r, such as a Replay, instead of os/exec.
+// Passing nil restores os/exec. It is meant for tests and for programs embedding tasky.
+func SetRunner(r Runner) {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	runner = r
+}
+
+// RunCmd runs a command with the current Runner and returns its stdout, trimmed. If the
+// command fails, it returns an error.
+func RunCmd(name string, arg ...string) (string, error) {
+	return runner.Run(name, arg...)
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"tasky/config"
+)
+
+// transliterations spell letters outside ASCII with ASCII ones. Letters with a diacritic
+// lose it: they are grouped by base letter, as in the "a" entry.
+var transliterations = func() map[rune]string {
+	m := map[rune]string{
+		'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th",
+		'ı': "i", 'ŋ': "ng", 'ĳ': "ij",
+		// Greek and Cyrillic
+		'α': "a", 'β': "b", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+		'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+		'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+		'ω': "o", 'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'ό': "o",
+		'ύ': "y", 'ϋ': "y", 'ΰ': "y", 'ώ': "o",
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+		'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+		'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+		'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+		'я': "ya", 'і': "i", 'ї': "i", 'є': "ye", 'ґ': "g",
+	}
+	for base, letters := range map[string]string{
+		"a": "àáâãäåāăą", "c": "çćĉċč", "d": "ď", "e": "èéêëēĕėęě", "g": "ĝğġģ",
+		"h": "ĥħ", "i": "ìíîïĩīĭį", "j": "ĵ", "k": "ķ", "l": "ĺļľŀ", "n": "ñńņňŉ",
+		"o": "òóôõöōŏő", "r": "ŕŗř", "s": "śŝşšș", "t": "ţťŧț", "u": "ùúûüũūŭůűų",
+		"w": "ŵ", "y": "ýÿŷ", "z": "źżž",
+	} {
+		for _, r := range letters {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// reservedNames cannot name a file on Windows, whatever their extension, so a vault
+// synced there could not hold them.
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// Slug turns title into lowercase ASCII words joined by hyphens, safe in file and branch
+// names: accented and other Latin, Greek and Cyrillic letters are transliterated, and
+// other characters, such as punctuation or emoji, separate words. The slug is cut at a
+// word boundary to at most maxLength bytes when possible, and never matches a reserved
+// file name. It is empty when title has no letter or digit.
+func Slug(title string, maxLength int) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case transliterations[r] != "":
+			sb.WriteString(transliterations[r])
+		case unicode.IsMark(r):
+			// Combining accents, as in decomposed "é", belong to the previous letter.
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	slug := strings.Join(strings.Fields(sb.String()), "-")
+	if maxLength > 0 && len(slug) > maxLength {
+		cut := slug[:maxLength]
+		if i := strings.LastIndex(cut, "-"); i > 0 && slug[maxLength] != '-' {
+			cut = cut[:i]
+		}
+		slug = strings.Trim(cut, "-")
+	}
+	if reservedNames[slug] {
+		slug += "-task"
+	}
+	return slug
+}
+
+// Slugify turns title into a slug of at most config.DefaultSlugLength bytes, see Slug.
+func Slugify(title string) string {
+	return Slug(title, config.DefaultSlugLength)
+}
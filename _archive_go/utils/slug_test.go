@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		title     string
+		maxLength int
+		want      string
+	}{
+		{"Fix login bug", 50, "fix-login-bug"},
+		{"  Fix: the *login* bug!  ", 50, "fix-the-login-bug"},
+		// Transliteration
+		{"Café crème brûlée", 50, "cafe-creme-brulee"},
+		{"Café", 50, "cafe"},
+		{"Straße Łódź", 50, "strasse-lodz"},
+		{"Ελληνικά", 50, "ellinika"},
+		{"Привет мир", 50, "privet-mir"},
+		{"Ship it 🚀 now", 50, "ship-it-now"},
+		{"🚀🎉", 50, ""},
+		// Truncation at a word boundary
+		{"fix the login bug", 9, "fix-the"},
+		{"fix the login bug", 7, "fix-the"},
+		{"fix the login bug", 8, "fix-the"},
+		{"internationalization", 5, "inter"},
+		{"fix the login bug", 0, "fix-the-login-bug"},
+		// Reserved names
+		{"CON", 50, "con-task"},
+		{"lpt1", 50, "lpt1-task"},
+		{"Con game", 50, "con-game"},
+	}
+	for _, tt := range tests {
+		got := Slug(tt.title, tt.maxLength)
+		if got != tt.want {
+			t.Errorf("Slug(%q, %d) = %q, want %q", tt.title, tt.maxLength, got, tt.want)
+		}
+		if tt.maxLength > 0 && len(got) > tt.maxLength {
+			t.Errorf("Slug(%q, %d) = %q, longer than the maximum", tt.title, tt.maxLength, got)
+		}
+	}
+}
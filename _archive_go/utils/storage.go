@@ -1,16 +1,134 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"tasky/config"
 )
 
-// GetTaskyDir returns the absolute path to the Tasky/ directory, creating it if it doesn't exist.
+// Storage backends selectable with `backend` in the [storage] section of config.toml.
+const (
+	BackendMarkdown = "markdown"
+	BackendBolt     = "bolt"
+)
+
+// TaskStore persists the task notes of a single project. Names are slash-separated paths
+// relative to the project's task root, e.g. "12-fix-login.md".
+type TaskStore interface {
+	// Get returns the content stored under name. Missing names yield an error wrapping fs.ErrNotExist.
+	Get(name string) ([]byte, error)
+	// List returns the names of every task note in the store.
+	List() ([]string, error)
+	// Put creates or replaces the content stored under name.
+	Put(name string, content []byte) error
+	// Delete removes name from the store.
+	Delete(name string) error
+	// Move renames oldName to newName, failing if newName already exists.
+	Move(oldName, newName string) error
+}
+
+// NoteInfo describes a stored note without reading it.
+type NoteInfo struct {
+	Name    string
+	ModTime time.Time
+	Size    int64
+}
+
+// IndexableStore is implemented by stores that can report modification times cheaply,
+// which lets callers cache parsed notes between runs.
+type IndexableStore interface {
+	TaskStore
+	// ListInfo returns the NoteInfo of every task note in the store.
+	ListInfo() ([]NoteInfo, error)
+	// IndexKey identifies the store across runs, e.g. its directory.
+	IndexKey() string
+}
+
+// LocatableStore is implemented by stores whose notes live in individual files.
+type LocatableStore interface {
+	TaskStore
+	// Location returns the file holding name.
+	Location(name string) string
+}
+
+// LockableStore is implemented by stores that can be locked against other tasky
+// processes, so that a read-modify-write of a note is not interleaved with another one.
+// The lock is advisory: programs other than tasky ignore it.
+type LockableStore interface {
+	TaskStore
+	// Lock takes the store's lock and returns the function releasing it. It is reentrant
+	// within a process.
+	Lock() (func(), error)
+}
+
+// LockStore locks store if it is a LockableStore. The returned function releases the
+// lock, and is a no-op for other stores.
+func LockStore(store TaskStore) (func(), error) {
+	lockable, ok := store.(LockableStore)
+	if !ok {
+		return func() {}, nil
+	}
+	return lockable.Lock()
+}
+
+// StoreBackend opens the TaskStore of each project and knows which projects exist.
+type StoreBackend interface {
+	Open(projectName string) (TaskStore, error)
+	Projects() ([]string, error)
+}
+
+// backendOverride replaces the configured backend when set, see SetStoreBackend.
+var backendOverride StoreBackend
+
+// SetStoreBackend makes every subsequent OpenTaskStore call use b instead of the backend
+// selected in the config. Passing nil restores the configured backend. It is meant for
+// tests and for programs embedding tasky.
+func SetStoreBackend(b StoreBackend) {
+	backendOverride = b
+}
+
+// NewStoreBackend returns the storage backend selected in the config.
+func NewStoreBackend(cfg config.Config) (StoreBackend, error) {
+	if backendOverride != nil {
+		return backendOverride, nil
+	}
+	switch cfg.Storage.Backend {
+	case "", BackendMarkdown:
+		return &MarkdownBackend{cfg: cfg}, nil
+	case BackendBolt:
+		path := cfg.Storage.Path
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(home, ".local", "share", "tasky", "tasks.db")
+		}
+		return NewBoltBackend(path), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend '%s'", cfg.Storage.Backend)
+	}
+}
+
+// OpenTaskStore returns the TaskStore of a project using the configured backend.
+func OpenTaskStore(cfg config.Config, projectName string) (TaskStore, error) {
+	backend, err := NewStoreBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Open(projectName)
+}
+
+// GetTaskyDir returns the absolute path to the project's notes directory, see TaskyDir,
+// creating it if it doesn't exist.
 func GetTaskyDir(cfg config.Config, projectName string) (string, error) {
-	taskyDir := filepath.Join(cfg.General.VaultPath, projectName, "Tasky")
+	taskyDir := TaskyDir(cfg, projectName)
 	if _, err := os.Stat(taskyDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(taskyDir, 0755); err != nil {
 			return "", fmt.Errorf("could not create Tasky directory: %w", err)
@@ -19,32 +137,159 @@ func GetTaskyDir(cfg config.Config, projectName string) (string, error) {
 	return taskyDir, nil
 }
 
-// CreateTaskyFile creates a new file inside the Tasky/ directory.
-func CreateTaskyFile(cfg config.Config, projectName string, fileName string) (*os.File, error) {
-	taskyDir, err := GetTaskyDir(cfg, projectName)
+// WriteToTaskyFile writes content to a file within the project's task store.
+func WriteToTaskyFile(cfg config.Config, projectName string, fileName string, content []byte) error {
+	store, err := OpenTaskStore(cfg, projectName)
+	if err != nil {
+		return err
+	}
+	return store.Put(fileName, content)
+}
+
+// ReadFromTaskyFile reads content from a file within the project's task store.
+func ReadFromTaskyFile(cfg config.Config, projectName string, fileName string) ([]byte, error) {
+	store, err := OpenTaskStore(cfg, projectName)
 	if err != nil {
 		return nil, err
 	}
-	filePath := filepath.Join(taskyDir, fileName)
-	return os.Create(filePath)
+	return store.Get(fileName)
 }
 
-// WriteToTaskyFile writes content to a file within the Tasky/ directory.
-func WriteToTaskyFile(cfg config.Config, projectName string, fileName string, content []byte) error {
-	taskyDir, err := GetTaskyDir(cfg, projectName)
+// MarkdownBackend stores notes as Markdown files in the vault Obsidian reads, under
+// <vault>/<project>/Tasky unless storage.layout places them elsewhere.
+type MarkdownBackend struct {
+	cfg config.Config
+}
+
+// Open returns the store of a project, creating its Tasky directory if needed.
+func (b *MarkdownBackend) Open(projectName string) (TaskStore, error) {
+	dir, err := GetTaskyDir(b.cfg, projectName)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkdownStore{Dir: dir}, nil
+}
+
+// Projects returns the projects with a notes directory in the vault, see LayoutProjects.
+func (b *MarkdownBackend) Projects() ([]string, error) {
+	return LayoutProjects(b.cfg.General.VaultPath, b.cfg.Storage.LayoutTemplate(), b.cfg.NoteFormat)
+}
+
+// MarkdownStore is a TaskStore backed by the Markdown files of a directory.
+type MarkdownStore struct {
+	Dir string
+}
+
+func (s *MarkdownStore) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid task name '%s'", name)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
+
+func (s *MarkdownStore) Get(name string) ([]byte, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (s *MarkdownStore) List() ([]string, error) {
+	infos, err := s.ListInfo()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	return names, nil
+}
+
+func (s *MarkdownStore) ListInfo() ([]NoteInfo, error) {
+	var infos []NoteInfo
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, NoteInfo{Name: filepath.ToSlash(rel), ModTime: info.ModTime(), Size: info.Size()})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return infos, err
+}
+
+func (s *MarkdownStore) Location(name string) string {
+	path, err := s.path(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func (s *MarkdownStore) IndexKey() string {
+	return s.Dir
+}
+
+func (s *MarkdownStore) Put(name string, content []byte) error {
+	path, err := s.path(name)
 	if err != nil {
 		return err
 	}
-	filePath := filepath.Join(taskyDir, fileName)
-	return os.WriteFile(filePath, content, 0644)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, content, 0644)
 }
 
-// ReadFromTaskyFile reads content from a file within the Tasky/ directory.
-func ReadFromTaskyFile(cfg config.Config, projectName string, fileName string) ([]byte, error) {
-	taskyDir, err := GetTaskyDir(cfg, projectName)
+// Lock takes a lock kept in the user cache directory, shared by every MarkdownStore of
+// the same directory.
+func (s *MarkdownStore) Lock() (func(), error) {
+	path, err := lockPath(s.Dir)
 	if err != nil {
 		return nil, err
 	}
-	filePath := filepath.Join(taskyDir, fileName)
-	return os.ReadFile(filePath)
+	return LockFile(path)
+}
+
+func (s *MarkdownStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *MarkdownStore) Move(oldName, newName string) error {
+	oldPath, err := s.path(oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := s.path(newName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("cannot move %s: %s already exists", oldName, newName)
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
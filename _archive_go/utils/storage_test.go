@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// storeFactories open an empty store of every TaskStore implementation.
+var storeFactories = map[string]func(t *testing.T) TaskStore{
+	"memory": func(t *testing.T) TaskStore {
+		return NewMemoryStore()
+	},
+	"markdown": func(t *testing.T) TaskStore {
+		return &MarkdownStore{Dir: t.TempDir()}
+	},
+	"bolt": func(t *testing.T) TaskStore {
+		store, err := NewBoltBackend(filepath.Join(t.TempDir(), "tasks.db")).Open("tasky")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	},
+}
+
+func TestTaskStores(t *testing.T) {
+	for name, open := range storeFactories {
+		t.Run(name, func(t *testing.T) {
+			t.Run("empty", func(t *testing.T) {
+				store := open(t)
+				assertNames(t, store)
+				if _, err := store.Get("missing.md"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Get of a missing note: got %v, want fs.ErrNotExist", err)
+				}
+				if err := store.Delete("missing.md"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Delete of a missing note: got %v, want fs.ErrNotExist", err)
+				}
+				if err := store.Move("missing.md", "other.md"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Move of a missing note: got %v, want fs.ErrNotExist", err)
+				}
+			})
+
+			t.Run("put and get", func(t *testing.T) {
+				store := open(t)
+				mustPut(t, store, "a.md", "first")
+				mustPut(t, store, "sub/b.md", "second")
+				mustPut(t, store, "a.md", "replaced")
+				mustPut(t, store, "empty.md", "")
+				assertContent(t, store, "a.md", "replaced")
+				assertContent(t, store, "sub/b.md", "second")
+				assertContent(t, store, "empty.md", "")
+				assertNames(t, store, "a.md", "empty.md", "sub/b.md")
+			})
+
+			t.Run("content is copied", func(t *testing.T) {
+				store := open(t)
+				content := []byte("original")
+				if err := store.Put("a.md", content); err != nil {
+					t.Fatal(err)
+				}
+				copy(content, "modified")
+				got, err := store.Get("a.md")
+				if err != nil {
+					t.Fatal(err)
+				}
+				copy(got, "modified")
+				assertContent(t, store, "a.md", "original")
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				store := open(t)
+				mustPut(t, store, "a.md", "first")
+				mustPut(t, store, "b.md", "second")
+				if err := store.Delete("a.md"); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := store.Get("a.md"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Get of a deleted note: got %v, want fs.ErrNotExist", err)
+				}
+				assertNames(t, store, "b.md")
+			})
+
+			t.Run("move", func(t *testing.T) {
+				store := open(t)
+				mustPut(t, store, "a.md", "first")
+				mustPut(t, store, "b.md", "second")
+				if err := store.Move("a.md", "sub/c.md"); err != nil {
+					t.Fatal(err)
+				}
+				assertContent(t, store, "sub/c.md", "first")
+				assertNames(t, store, "b.md", "sub/c.md")
+
+				if err := store.Move("b.md", "sub/c.md"); err == nil {
+					t.Error("Move onto an existing note succeeded")
+				}
+				assertContent(t, store, "b.md", "second")
+				assertContent(t, store, "sub/c.md", "first")
+			})
+		})
+	}
+}
+
+func TestBoltBackendProjects(t *testing.T) {
+	backend := NewBoltBackend(filepath.Join(t.TempDir(), "tasks.db"))
+	projects, err := backend.Projects()
+	if err != nil || len(projects) != 0 {
+		t.Fatalf("Projects of a new database: got %v, %v", projects, err)
+	}
+	for _, project := range []string{"web", "api"} {
+		store, err := backend.Open(project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustPut(t, store, "note.md", project)
+	}
+	projects, err = backend.Projects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api", "web"}; !reflect.DeepEqual(projects, want) {
+		t.Errorf("Projects: got %v, want %v", projects, want)
+	}
+	store, _ := backend.Open("api")
+	assertContent(t, store, "note.md", "api")
+}
+
+func mustPut(t *testing.T, store TaskStore, name, content string) {
+	t.Helper()
+	if err := store.Put(name, []byte(content)); err != nil {
+		t.Fatalf("Put(%s): %v", name, err)
+	}
+}
+
+func assertContent(t *testing.T, store TaskStore, name, want string) {
+	t.Helper()
+	got, err := store.Get(name)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", name, err)
+	}
+	if string(got) != want {
+		t.Errorf("Get(%s): got %q, want %q", name, got, want)
+	}
+}
+
+func assertNames(t *testing.T, store TaskStore, want ...string) {
+	t.Helper()
+	got, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	sort.Strings(got)
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend keeps the notes of every project in a single bbolt database, for people
+// who don't keep their tasks in an Obsidian vault. Each project is a bucket mapping note
+// names to their content.
+type BoltBackend struct {
+	Path string
+}
+
+// NewBoltBackend returns a BoltBackend reading and writing the database at path.
+func NewBoltBackend(path string) *BoltBackend {
+	return &BoltBackend{Path: path}
+}
+
+// boltTimeout bounds how long opening the database waits for another tasky process
+// holding it.
+const boltTimeout = 10 * time.Second
+
+// view runs fn in a read-only transaction. A database that doesn't exist yet reads as
+// empty: fn is not called and view reports false.
+func (b *BoltBackend) view(fn func(tx *bolt.Tx) error) (bool, error) {
+	if _, err := os.Stat(b.Path); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	db, err := bolt.Open(b.Path, 0644, &bolt.Options{Timeout: boltTimeout, ReadOnly: true})
+	if err != nil {
+		return false, fmt.Errorf("error opening task database %s: %w", b.Path, err)
+	}
+	defer db.Close()
+	return true, db.View(fn)
+}
+
+// update runs fn in a read-write transaction, creating the database if needed.
+func (b *BoltBackend) update(fn func(tx *bolt.Tx) error) error {
+	if err := os.MkdirAll(filepath.Dir(b.Path), 0755); err != nil {
+		return err
+	}
+	db, err := bolt.Open(b.Path, 0644, &bolt.Options{Timeout: boltTimeout})
+	if err != nil {
+		return fmt.Errorf("error opening task database %s: %w", b.Path, err)
+	}
+	if err := db.Update(fn); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// lock takes the lock of the database, which every project shares. The database's own
+// lock only lasts for a transaction, so a read-modify-write needs this one.
+func (b *BoltBackend) lock() (func(), error) {
+	return LockFile(b.Path + ".lock")
+}
+
+func (b *BoltBackend) Open(projectName string) (TaskStore, error) {
+	if projectName == "" {
+		return nil, fmt.Errorf("a project name is required")
+	}
+	return &BoltStore{backend: b, bucket: []byte(projectName)}, nil
+}
+
+func (b *BoltBackend) Projects() ([]string, error) {
+	var projects []string
+	_, err := b.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			projects = append(projects, string(name))
+			return nil
+		})
+	})
+	return projects, err
+}
+
+// BoltStore is the TaskStore of one project inside a BoltBackend.
+type BoltStore struct {
+	backend *BoltBackend
+	bucket  []byte
+}
+
+// update runs fn on the project's bucket, creating it if needed.
+func (s *BoltStore) update(fn func(notes *bolt.Bucket) error) error {
+	return s.backend.update(func(tx *bolt.Tx) error {
+		notes, err := tx.CreateBucketIfNotExists(s.bucket)
+		if err != nil {
+			return err
+		}
+		return fn(notes)
+	})
+}
+
+func (s *BoltStore) Lock() (func(), error) {
+	return s.backend.lock()
+}
+
+func (s *BoltStore) Get(name string) ([]byte, error) {
+	var content []byte
+	_, err := s.backend.view(func(tx *bolt.Tx) error {
+		if notes := tx.Bucket(s.bucket); notes != nil {
+			if value := notes.Get([]byte(name)); value != nil {
+				// Values are only valid during the transaction.
+				content = append([]byte{}, value...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return content, nil
+}
+
+// List returns the note names in byte order, the order of the bucket's keys.
+func (s *BoltStore) List() ([]string, error) {
+	var names []string
+	_, err := s.backend.view(func(tx *bolt.Tx) error {
+		notes := tx.Bucket(s.bucket)
+		if notes == nil {
+			return nil
+		}
+		return notes.ForEach(func(name, _ []byte) error {
+			names = append(names, string(name))
+			return nil
+		})
+	})
+	return names, err
+}
+
+func (s *BoltStore) Put(name string, content []byte) error {
+	if name == "" {
+		return fmt.Errorf("invalid task name '%s'", name)
+	}
+	return s.update(func(notes *bolt.Bucket) error {
+		// A nil value would read back as a missing note.
+		return notes.Put([]byte(name), append([]byte{}, content...))
+	})
+}
+
+func (s *BoltStore) Delete(name string) error {
+	return s.update(func(notes *bolt.Bucket) error {
+		if notes.Get([]byte(name)) == nil {
+			return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+		}
+		return notes.Delete([]byte(name))
+	})
+}
+
+func (s *BoltStore) Move(oldName, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("invalid task name '%s'", newName)
+	}
+	return s.update(func(notes *bolt.Bucket) error {
+		content := notes.Get([]byte(oldName))
+		if content == nil {
+			return fmt.Errorf("%s: %w", oldName, fs.ErrNotExist)
+		}
+		if notes.Get([]byte(newName)) != nil {
+			return fmt.Errorf("cannot move %s: %s already exists", oldName, newName)
+		}
+		if err := notes.Put([]byte(newName), append([]byte{}, content...)); err != nil {
+			return err
+		}
+		return notes.Delete([]byte(oldName))
+	})
+}
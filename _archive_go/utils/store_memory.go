@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// MemoryBackend keeps every project's notes in memory. It is meant for tests.
+type MemoryBackend struct {
+	mu     sync.Mutex
+	stores map[string]*MemoryStore
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{stores: make(map[string]*MemoryStore)}
+}
+
+func (b *MemoryBackend) Open(projectName string) (TaskStore, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	store, ok := b.stores[projectName]
+	if !ok {
+		store = NewMemoryStore()
+		b.stores[projectName] = store
+	}
+	return store, nil
+}
+
+func (b *MemoryBackend) Projects() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var projects []string
+	for name := range b.stores {
+		projects = append(projects, name)
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// MemoryStore is a TaskStore holding notes in a map.
+type MemoryStore struct {
+	mu    sync.Mutex
+	notes map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{notes: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.notes[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return append([]byte(nil), content...), nil
+}
+
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.notes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemoryStore) Put(name string, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes[name] = append([]byte(nil), content...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.notes[name]; !ok {
+		return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	delete(s.notes, name)
+	return nil
+}
+
+func (s *MemoryStore) Move(oldName, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.notes[oldName]
+	if !ok {
+		return fmt.Errorf("%s: %w", oldName, fs.ErrNotExist)
+	}
+	if _, exists := s.notes[newName]; exists {
+		return fmt.Errorf("cannot move %s: %s already exists", oldName, newName)
+	}
+	s.notes[newName] = content
+	delete(s.notes, oldName)
+	return nil
+}
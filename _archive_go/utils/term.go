@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// stty runs stty against the controlling terminal and returns its output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsTerminal reports whether stdin is an interactive terminal.
+func IsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// MakeRaw puts the terminal in raw mode, so single keypresses can be read without
+// waiting for Enter and without being echoed. Reads from stdin then return after at most
+// a tenth of a second even when no key was pressed (with io.EOF and no data), which lets
+// callers poll for keys from their main loop. The returned function restores the
+// previous settings.
+func MakeRaw() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo", "min", "0", "time", "1"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(saved)
+	}, nil
+}
+
+// TerminalSize returns the number of columns and rows of the terminal, falling back to
+// 80x24 when it cannot be determined.
+func TerminalSize() (int, int) {
+	output, err := stty("size")
+	if err == nil {
+		var rows, cols int
+		if _, err := fmt.Sscanf(output, "%d %d", &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return cols, rows
+		}
+	}
+	return 80, 24
+}
+
+// Special keys returned by ReadKey. They are control sequences, so they can never be
+// confused with typed text.
+const (
+	KeyUp        = "\x1b[A"
+	KeyDown      = "\x1b[B"
+	KeyRight     = "\x1b[C"
+	KeyLeft      = "\x1b[D"
+	KeyEnter     = "\r"
+	KeyEscape    = "\x1b"
+	KeyBackspace = "\x7f"
+	KeyCtrlC     = "\x03"
+)
+
+// pendingInput holds bytes read from the terminal but not yet returned by ReadKey, e.g.
+// when several keys arrive in a single read.
+var pendingInput []byte
+
+// ReadKey reads one keypress from a terminal in raw mode. Printable input is returned
+// one character at a time, special keys as one of the Key constants, and "" when no key
+// was pressed before the read timed out or the key is not supported.
+func ReadKey() string {
+	if len(pendingInput) == 0 && !readInput() {
+		return ""
+	}
+	key, size := parseKey(pendingInput)
+	if size == 0 {
+		// An escape sequence split across reads.
+		if !readInput() {
+			pendingInput = nil
+			return ""
+		}
+		if key, size = parseKey(pendingInput); size == 0 {
+			pendingInput = nil
+			return ""
+		}
+	}
+	pendingInput = pendingInput[size:]
+	return key
+}
+
+// readInput appends what the terminal has to pendingInput, reporting whether there was
+// anything.
+func readInput() bool {
+	buf := make([]byte, 64)
+	n, err := os.Stdin.Read(buf)
+	if err != nil || n == 0 {
+		return false
+	}
+	pendingInput = append(pendingInput, buf[:n]...)
+	return true
+}
+
+// parseKey returns the key at the start of input, as ReadKey does, and its length in
+// bytes. The length is 0 when input ends in the middle of an escape sequence. A CSI
+// sequence, such as "\x1b[3~" for Delete or "\x1b[1;5A" for Ctrl+Up, runs up to its
+// final byte, 0x40 to 0x7E; an SS3 sequence, as some terminals send for the arrows, is
+// three bytes long.
+func parseKey(input []byte) (string, int) {
+	if input[0] == 27 && len(input) >= 2 {
+		switch input[1] {
+		case '[':
+			for i := 2; i < len(input); i++ {
+				if input[i] >= 0x40 && input[i] <= 0x7e {
+					switch key := string(input[:i+1]); key {
+					case KeyUp, KeyDown, KeyRight, KeyLeft:
+						return key, i + 1
+					}
+					return "", i + 1
+				}
+			}
+			return "", 0
+		case 'O':
+			if len(input) < 3 {
+				return "", 0
+			}
+			switch input[2] {
+			case 'A', 'B', 'C', 'D':
+				return "\x1b[" + string(input[2]), 3
+			}
+			return "", 3
+		}
+	}
+	if input[0] == 27 {
+		return KeyEscape, 1
+	}
+
+	r, size := utf8.DecodeRune(input)
+	switch {
+	case r == '\r' || r == '\n':
+		return KeyEnter, size
+	case r == 127 || r == 8:
+		return KeyBackspace, size
+	case r == 3:
+		return KeyCtrlC, size
+	case r < 32 || r == utf8.RuneError:
+		return "", size
+	}
+	return string(r), size
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // keys read one after the other
+	}{
+		{"text", "hé!", []string{"h", "é", "!"}},
+		{"arrows", "\x1b[A\x1b[B\x1b[C\x1b[D", []string{KeyUp, KeyDown, KeyRight, KeyLeft}},
+		{"SS3 arrows", "\x1bOA\x1bOD", []string{KeyUp, KeyLeft}},
+		{"delete", "\x1b[3~x", []string{"", "x"}},
+		{"page up and home", "\x1b[5~\x1b[1~a", []string{"", "", "a"}},
+		{"modified arrow", "\x1b[1;5Ab", []string{"", "b"}},
+		{"function key", "\x1bOPc", []string{"", "c"}},
+		{"escape", "\x1b", []string{KeyEscape}},
+		{"controls", "\r\x7f\x03\x01", []string{KeyEnter, KeyBackspace, KeyCtrlC, ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := []byte(tt.input)
+			var got []string
+			for len(input) > 0 {
+				key, size := parseKey(input)
+				if size == 0 {
+					t.Fatalf("incomplete key at %q", input)
+				}
+				got = append(got, key)
+				input = input[size:]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyIncomplete(t *testing.T) {
+	for _, input := range []string{"\x1b[", "\x1b[3", "\x1b[1;5", "\x1bO"} {
+		if key, size := parseKey([]byte(input)); size != 0 {
+			t.Errorf("parseKey(%q) = %q, %d; want an incomplete sequence", input, key, size)
+		}
+	}
+}